@@ -4,30 +4,38 @@ go 1.21
 
 toolchain go1.21.1
 
-require rsc.io/quote v1.5.2
+require (
+	github.com/fasthttp/websocket v1.5.7
+	github.com/gofiber/contrib/websocket v1.3.0
+	rsc.io/quote v1.5.2
+)
 
 require (
-	github.com/andybalholm/brotli v1.0.5 // indirect
 	github.com/google/uuid v1.5.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
-	github.com/klauspost/compress v1.17.0 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/klauspost/compress v1.17.3 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
+	github.com/savsgio/gotils v0.0.0-20230208104028-c358bd845dee // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasthttp v1.51.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 )
 
 require (
+	github.com/andybalholm/brotli v1.0.5
 	github.com/asaskevich/EventBus v0.0.0-20200907212545-49d423059eef
 	github.com/gofiber/fiber/v2 v2.52.5
 	github.com/jackc/pgx/v5 v5.7.1
 	github.com/joho/godotenv v1.5.1
-	golang.org/x/text v0.18.0 // indirect
+	golang.org/x/text v0.18.0
 	rsc.io/sampler v1.3.0 // indirect
 )
@@ -2,113 +2,410 @@ package main
 
 import (
 	"anify/eltik/go/src/database"
+	database_migrations "anify/eltik/go/src/database/impl/migrations"
+	database_repository "anify/eltik/go/src/database/impl/repository"
 	events "anify/eltik/go/src/lib"
+	"anify/eltik/go/src/lib/impl/crawler"
 	"anify/eltik/go/src/lib/impl/mappings"
 	proxies "anify/eltik/go/src/lib/impl/proxies"
-	"anify/eltik/go/src/lib/impl/request"
+	"anify/eltik/go/src/lib/impl/queue"
+	"anify/eltik/go/src/lib/impl/webhooks"
+	providers "anify/eltik/go/src/mappings"
+	"anify/eltik/go/src/server"
 	"anify/eltik/go/src/types"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
 	events.Listen()
-	database.Connect()
-	database.CreateTables()
+	pool, _ := database.Connect()
+	defer pool.Close()
+
+	if command == "migrate" {
+		migrate(pool, os.Args[2:])
+		return
+	}
+
+	if _, err := database_migrations.Up(context.Background(), pool, 0); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
 
 	proxies.FetchCorsProxies()
 
-	proxy := request.GetRandomUnbannedProxy("mangadex")
-	if proxy != nil {
-		println(*proxy)
+	if err := mappings.LoadMatchConfigs(os.Getenv("MATCH_CONFIG")); err != nil {
+		log.Fatalf("Invalid MATCH_CONFIG: %v", err)
+	}
+
+	repo := database_repository.New(pool)
+
+	switch command {
+	case "serve":
+		serve(repo)
+	case "map":
+		loadMappings(repo, os.Args[2:])
+	case "enqueue":
+		enqueue(repo, os.Args[2:])
+	case "worker":
+		work(repo, os.Args[2:])
+	case "jobs":
+		listJobs(repo, os.Args[2:])
+	case "crawl":
+		crawl(repo, os.Args[2:])
+	case "chapters":
+		refreshChapters(repo, os.Args[2:])
+	case "info":
+		refreshInformation(repo, os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\nUsage: go run . [serve | map <id> [type] [format] | enqueue <id> [type] [format] | worker [n] | jobs [state] | crawl [type] [--dry-run] [--queue] | chapters <id> | info <id> | migrate [up|down|status] [n]]\n", command)
+		os.Exit(1)
 	}
+}
+
+// serve starts the HTTP API on PORT (default 3000) along with QUEUE_WORKERS mapping
+// workers (default 2, 0 disables them), webhook delivery and proxy checks.
+func serve(repo database.Repository) {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+	}
+
+	workers := queue.DefaultConfig.Workers
+	if value := os.Getenv("QUEUE_WORKERS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid QUEUE_WORKERS: %s", value)
+		}
+		workers = parsed
+	}
+
+	if workers > 0 {
+		config := queue.DefaultConfig
+		config.Workers = workers
+		go queue.New(repo, config).Run(context.Background())
+	}
+
+	go webhooks.New(repo, webhooks.DefaultConfig).Run(context.Background())
+
+	if interval := proxyCheckInterval(); interval > 0 {
+		go providers.RunProxyChecks(context.Background(), interval)
+	}
+
+	if err := server.Start(port, repo); err != nil {
+		log.Fatalf("Server stopped: %v", err)
+	}
+}
+
+// loadMappings runs the mapping pipeline for a single ID. The type defaults to
+// MANGA and the format to MANGA.
+func loadMappings(repo database.Repository, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go run . map <id> [type] [format]")
+		os.Exit(1)
+	}
+
+	type_, format := parseMediaArgs(args)
 
-	mappings.LoadMappings(struct {
+	_, _, err := mappings.LoadMappings(context.Background(), repo, struct {
 		ID      string
 		Type    types.Type
 		Formats []types.Format
 	}{
-		ID:      "cde5424f-02e5-4c90-a433-b92d831d9856",
-		Type:    types.TypeManga,
-		Formats: []types.Format{types.FormatManga},
+		ID:      args[0],
+		Type:    type_,
+		Formats: []types.Format{format},
 	})
+	if err != nil {
+		log.Fatalf("Failed to load mappings: %v", err)
+	}
+}
 
-	/*
-		res, err := base.NewMangaDexBaseProvider().GetIds()
-		if err != nil {
-			panic(err)
-		}
+// refreshChapters fetches the chapters of a stored manga again and saves them.
+func refreshChapters(repo database.Repository, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go run . chapters <id>")
+		os.Exit(1)
+	}
 
-		println(len(res))
-	*/
+	manga, err := mappings.RefreshChapters(context.Background(), repo, args[0])
+	if err != nil {
+		log.Fatalf("Failed to refresh chapters: %v", err)
+	}
+	if manga == nil {
+		log.Fatalf("Manga not found: %s", args[0])
+	}
 
-	/*
-		res, err := base.NewMangaDexBaseProvider().SearchAdvanced("", types.TypeManga, []types.Format{types.FormatManga}, 0, 25, []string{"Aliens"}, []string{"Harem"}, types.SeasonUnknown, 0, []string{"Isekai"}, []string{"Harem"})
-		if err != nil {
-			panic(err)
-		}
+	fmt.Printf("Latest chapter of %s: %g\n", manga.ID, manga.Chapters.Latest.LatestChapter)
+}
 
-		for _, r := range res {
-			// print r.title.english
-			println(*r.Title.English)
-		}
-	*/
+// refreshInformation runs the information providers again for a stored ID and
+// prints the merged averages.
+func refreshInformation(repo database.Repository, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go run . info <id>")
+		os.Exit(1)
+	}
 
-	/*
-		res, err := base.NewMangaDexBaseProvider().GetMedia("ed5dddc0-999d-4fd2-a702-9fef1ec2c955")
-		if err != nil {
-			panic(err)
+	media, err := mappings.RefreshInformation(context.Background(), repo, args[0])
+	if err != nil {
+		log.Fatalf("Failed to refresh information: %v", err)
+	}
+	if media == nil {
+		log.Fatalf("Media not found: %s", args[0])
+	}
+
+	fmt.Printf("Merged information for %s: %d synonyms, %d genres, %d tags, %d artwork\n", media.ID, len(media.Synonyms), len(media.Genres), len(media.Tags), len(media.Artwork))
+	if media.AverageRating != nil {
+		fmt.Printf("Average rating: %.2f\n", *media.AverageRating)
+	}
+	if media.AveragePopularity != nil {
+		fmt.Printf("Average popularity: %.0f\n", *media.AveragePopularity)
+	}
+}
+
+// enqueue queues the mapping pipeline for a single ID so a worker picks it up. The
+// type and format default the same way as for map.
+func enqueue(repo database.Repository, args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: go run . enqueue <id> [type] [format]")
+		os.Exit(1)
+	}
+
+	type_, format := parseMediaArgs(args)
+
+	job, err := queue.New(repo, queue.DefaultConfig).Enqueue(context.Background(), args[0], type_, []types.Format{format})
+	if err != nil {
+		log.Fatalf("Failed to enqueue job: %v", err)
+	}
+
+	fmt.Printf("Queued job %d (%s %s, formats %v)\n", job.ID, job.Type, job.MediaID, job.Formats)
+}
+
+// work runs n mapping workers (default 2) and webhook delivery until the process is
+// stopped.
+func work(repo database.Repository, args []string) {
+	config := queue.DefaultConfig
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid number of workers: %s", args[0])
 		}
+		config.Workers = n
+	}
 
-		println(*res.Title.English)
-	*/
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	/*
-		res, err := base.NewMangaDexBaseProvider().GetSeasonal(types.TypeAnime, []types.Format{types.FormatManga})
-		if err != nil {
-			panic(err)
+	go webhooks.New(repo, webhooks.DefaultConfig).Run(ctx)
+
+	if interval := proxyCheckInterval(); interval > 0 {
+		go providers.RunProxyChecks(ctx, interval)
+	}
+
+	fmt.Printf("Starting %d worker(s)\n", config.Workers)
+	queue.New(repo, config).Run(ctx)
+}
+
+// proxyCheckInterval reads PROXY_CHECK_INTERVAL, such as "10m". It defaults to
+// providers.DefaultProxyCheckInterval and 0 disables proxy checks.
+func proxyCheckInterval() time.Duration {
+	value := os.Getenv("PROXY_CHECK_INTERVAL")
+	if value == "" {
+		return providers.DefaultProxyCheckInterval
+	}
+	if value == "0" {
+		return 0
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid PROXY_CHECK_INTERVAL: %s", value)
+	}
+	return interval
+}
+
+// listJobs prints the most recently updated jobs, optionally only those in one state.
+func listJobs(repo database.Repository, args []string) {
+	var state database.JobState
+	if len(args) > 0 {
+		state = database.JobState(strings.ToLower(args[0]))
+	}
+
+	jobs, err := repo.ListJobs(context.Background(), state, 50)
+	if err != nil {
+		log.Fatalf("Failed to list jobs: %v", err)
+	}
+
+	for _, job := range jobs {
+		line := fmt.Sprintf("%d\t%-7s\t%s %s\t%d/%d attempts\tupdated %s", job.ID, job.State, job.Type, job.MediaID, job.Attempts, job.MaxAttempts, job.UpdatedAt.Format("2006-01-02 15:04:05"))
+		if job.LastError != nil {
+			line += "\t" + *job.LastError
 		}
+		fmt.Println(line)
+	}
+}
 
-		println("Popular:")
+// crawl maps every ID of the base provider for a type (default MANGA) that is not
+// stored yet. --dry-run only reports what would be crawled and --queue adds the IDs
+// to the mapping queue instead of mapping them here. Progress is saved to
+// crawl-<type>.checkpoint.json so an interrupted crawl resumes where it stopped.
+func crawl(repo database.Repository, args []string) {
+	type_ := types.TypeManga
+	dryRun := false
+	useQueue := false
 
-		for _, r := range res.Popular {
-			println(*r.Title.English)
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run":
+			dryRun = true
+		case "--queue":
+			useQueue = true
+		default:
+			type_ = types.Type(strings.ToUpper(arg))
 		}
+	}
 
-		println("Seasonal:")
+	formats := queue.DefaultFormats(type_)
 
-		for _, r := range res.Seasonal {
-			println(*r.Title.English)
+	var provider types.BaseProvider
+	for _, p := range *providers.GetBaseProviders() {
+		for _, supported := range p.GetFormats() {
+			if supported == formats[0] {
+				provider = p
+				break
+			}
+		}
+		if provider != nil {
+			break
 		}
+	}
+	if provider == nil {
+		log.Fatalf("No base provider supports %s", type_)
+	}
 
-		println("Top:")
+	opts := crawler.Options{
+		Type:           type_,
+		Formats:        formats,
+		CheckpointPath: fmt.Sprintf("crawl-%s.checkpoint.json", strings.ToLower(string(type_))),
+		DryRun:         dryRun,
+	}
 
-		for _, r := range res.Top {
-			println(*r.Title.English)
+	if useQueue {
+		q := queue.New(repo, queue.DefaultConfig)
+		opts.Queue = func(ctx context.Context, id string) error {
+			_, err := q.Enqueue(ctx, id, type_, formats)
+			return err
 		}
+	}
 
-		println("Trending:")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		for _, r := range res.Trending {
-			println(*r.Title.English)
+	result, err := crawler.Crawl(ctx, repo, provider, opts)
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("Crawl interrupted after %d done and %d failed, progress saved to %s\n", result.Done, result.Failed, opts.CheckpointPath)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Crawl failed: %v", err)
+	}
+
+	if dryRun {
+		for _, id := range result.Pending {
+			fmt.Println(id)
 		}
-	*/
+		return
+	}
+
+	fmt.Printf("Crawl finished: %d done, %d failed\n", result.Done, result.Failed)
+}
+
+// parseMediaArgs reads the optional type and format after an ID, defaulting both to
+// MANGA.
+func parseMediaArgs(args []string) (types.Type, types.Format) {
+	type_ := types.TypeManga
+	if len(args) > 1 {
+		type_ = types.Type(strings.ToUpper(args[1]))
+	}
+
+	format := types.FormatManga
+	if len(args) > 2 {
+		format = types.Format(strings.ToUpper(args[2]))
+	}
+
+	return type_, format
+}
+
+// migrate applies or rolls back schema migrations, or prints their status.
+// "up [version]" migrates up to version (default latest) and "down [n]" rolls back
+// the last n migrations (default 1).
+func migrate(db database.Querier, args []string) {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
 
-	/*
-		resp, err := request.Request("novelupdates", false, http.Request{
-			URL: &url.URL{
-				Scheme: "https",
-				Host:   "www.novelupdates.com",
-			},
-			Method: "GET",
-		}, true)
+	n := 0
+	if len(args) > 1 {
+		parsed, err := strconv.Atoi(args[1])
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("Invalid number: %s", args[1])
 		}
+		n = parsed
+	}
 
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := database_migrations.Up(ctx, db, n)
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
 		if err != nil {
-			log.Fatalf("Error reading response body: %v", err)
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		if n == 0 {
+			n = 1
+		}
+		reverted, err := database_migrations.Down(ctx, db, n)
+		for _, m := range reverted {
+			fmt.Printf("Rolled back %04d_%s\n", m.Version, m.Name)
 		}
-		log.Printf("Response Body: %s\n", string(body))
-	*/
+		if err != nil {
+			log.Fatalf("Failed to roll back: %v", err)
+		}
+	case "status":
+		statuses, err := database_migrations.GetStatus(ctx, db)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("[x] %04d_%s (applied %s)\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("[ ] %04d_%s\n", status.Version, status.Name)
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate action: %s\nUsage: go run . migrate [up [version] | down [n] | status]\n", action)
+		os.Exit(1)
+	}
 }
@@ -1,21 +1,41 @@
 package database
 
 import (
+	"anify/eltik/go/src/types"
 	"context"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 )
 
 var (
 	// DB is the connection pool to the database
-	DB *pgx.Conn
+	DB *pgxpool.Pool
 )
 
-func Connect() (*pgx.Conn, error) {
+// Querier is implemented by *pgxpool.Pool, *pgx.Conn and pgx.Tx so queries can run
+// against the pool or inside a transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// LockMedia serialises writes to the mappings and mapping overrides of one entry
+// until the transaction ends, so an override cannot be lost to a concurrent write of
+// the entry.
+func LockMedia(ctx context.Context, tx pgx.Tx, id string, type_ types.Type) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('media/' || $1 || '/' || $2))`, string(type_), id)
+	return err
+}
+
+func Connect() (*pgxpool.Pool, error) {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatalf("Error loading .env file: %v", err)
@@ -23,16 +43,16 @@ func Connect() (*pgx.Conn, error) {
 
 	println("Connecting to database at", os.Getenv("DATABASE_URL"))
 
-	conn, err := pgx.Connect(context.Background(), os.Getenv("DATABASE_URL"))
+	pool, err := pgxpool.New(context.Background(), os.Getenv("DATABASE_URL"))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
 		os.Exit(1)
 		return nil, err
 	}
 
-	DB = conn
+	DB = pool
 
-	err = conn.Ping(context.Background())
+	err = pool.Ping(context.Background())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "QueryRow failed: %v\n", err)
 		os.Exit(1)
@@ -40,5 +60,5 @@ func Connect() (*pgx.Conn, error) {
 
 	println("Connected to database")
 
-	return conn, nil
+	return pool, nil
 }
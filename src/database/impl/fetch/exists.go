@@ -0,0 +1,39 @@
+package database_fetch
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+	"fmt"
+)
+
+// ExistingIDs returns which of the given IDs already have a row in the anime or
+// manga table.
+func ExistingIDs(ctx context.Context, db database.Querier, type_ types.Type, ids []string) (map[string]bool, error) {
+	var table string
+	switch type_ {
+	case types.TypeAnime:
+		table = "anime"
+	case types.TypeManga:
+		table = "manga"
+	default:
+		return nil, fmt.Errorf("unknown type: %s", type_)
+	}
+
+	rows, err := db.Query(ctx, `SELECT id FROM `+table+` WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+
+	return existing, rows.Err()
+}
@@ -8,29 +8,56 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-func Get(id string, type_ types.Type) (interface{}, error) {
+// Get fetches an anime or manga by its ID. It returns nil when nothing is found.
+func Get(ctx context.Context, db database.Querier, id string, type_ types.Type) (interface{}, error) {
 	switch type_ {
 	case types.TypeAnime:
-		return GetAnimeByID(id)
+		anime, err := GetAnimeByID(ctx, db, id)
+		if err != nil || anime == nil {
+			return nil, err
+		}
+		return anime, nil
 	case types.TypeManga:
-		return GetMangaByID(id)
+		manga, err := GetMangaByID(ctx, db, id)
+		if err != nil || manga == nil {
+			return nil, err
+		}
+		return manga, nil
 	}
 
 	return nil, nil
 }
 
+const animeColumns = `
+	id, artwork, "averagePopularity", "averageRating", "bannerImage", characters, color,
+	"countryOfOrigin", "coverImage", "currentEpisode", description, duration, episodes,
+	format, genres, mappings, popularity, rating, relations, season, slug, status,
+	synonyms, tags, title, "totalEpisodes", trailer, type, year, provenance
+`
+
+const mangaColumns = `
+	id, artwork, "averagePopularity", "averageRating", "bannerImage", color, "countryOfOrigin",
+	"coverImage", "currentChapter", description, format, genres, mappings, popularity,
+	rating, relations, slug, status, synonyms, title, "totalChapters",
+	"totalVolumes", type, year, chapters, tags, characters, provenance
+`
+
 // GetAnimeByID fetches an anime by its ID.
-func GetAnimeByID(id string) (*types.Anime, error) {
-	var anime types.Anime
+func GetAnimeByID(ctx context.Context, db database.Querier, id string) (*types.Anime, error) {
+	anime, err := scanAnime(db.QueryRow(ctx, `SELECT `+animeColumns+` FROM anime WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
 
-	err := database.DB.QueryRow(context.Background(), `
-		SELECT id, artwork, "averagePopularity", "averageRating", "bannerImage", characters, color,
-		       "countryOfOrigin", "coverImage", "currentEpisode", description, duration, episodes,
-		       format, genres, mappings, popularity, rating, relations, season, slug, status,
-		       synonyms, tags, title, "totalEpisodes", trailer, type, year
-		FROM anime
-		WHERE id = $1
-	`, id).Scan(&anime.ID, &anime.Artwork, &anime.AveragePopularity, &anime.AverageRating, &anime.BannerImage, &anime.Characters, &anime.Color, &anime.CountryOfOrigin, &anime.CoverImage, &anime.CurrentEpisode, &anime.Description, &anime.Duration, &anime.Episodes, &anime.Format, &anime.Genres, &anime.Mappings, &anime.Popularity, &anime.Rating, &anime.Relations, &anime.Season, &anime.Slug, &anime.Status, &anime.Synonyms, &anime.Tags, &anime.Title, &anime.TotalEpisodes, &anime.Trailer, &anime.Type, &anime.Year)
+	return anime, nil
+}
+
+// GetMangaByID fetches a manga by its ID.
+func GetMangaByID(ctx context.Context, db database.Querier, id string) (*types.Manga, error) {
+	manga, err := scanManga(db.QueryRow(ctx, `SELECT `+mangaColumns+` FROM manga WHERE id = $1`, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -38,25 +65,27 @@ func GetAnimeByID(id string) (*types.Anime, error) {
 		return nil, err
 	}
 
+	return manga, nil
+}
+
+// scanAnime reads a row selected with animeColumns.
+func scanAnime(row pgx.Row) (*types.Anime, error) {
+	var anime types.Anime
+
+	err := row.Scan(&anime.ID, &anime.Artwork, &anime.AveragePopularity, &anime.AverageRating, &anime.BannerImage, &anime.Characters, &anime.Color, &anime.CountryOfOrigin, &anime.CoverImage, &anime.CurrentEpisode, &anime.Description, &anime.Duration, &anime.Episodes, &anime.Format, &anime.Genres, &anime.Mappings, &anime.Popularity, &anime.Rating, &anime.Relations, &anime.Season, &anime.Slug, &anime.Status, &anime.Synonyms, &anime.Tags, &anime.Title, &anime.TotalEpisodes, &anime.Trailer, &anime.Type, &anime.Year, &anime.Provenance)
+	if err != nil {
+		return nil, err
+	}
+
 	return &anime, nil
 }
 
-func GetMangaByID(id string) (*types.Manga, error) {
+// scanManga reads a row selected with mangaColumns.
+func scanManga(row pgx.Row) (*types.Manga, error) {
 	var manga types.Manga
 
-	err := database.DB.QueryRow(context.Background(), `
-		SELECT id, artwork, "averagePopularity", "averageRating", "bannerImage", color, "countryOfOrigin",
-			   "coverImage", "currentChapter", description, format, genres, mappings, popularity,
-			   rating, relations, slug, status, synonyms, title, "totalChapters",
-			   "totalVolumes", type, year							
-		FROM manga
-		WHERE id = $1
-	`, id).Scan(&manga.ID, &manga.Artwork, &manga.AveragePopularity, &manga.AverageRating, &manga.BannerImage, &manga.Color, &manga.CountryOfOrigin, &manga.CoverImage, &manga.CurrentChapter, &manga.Description, &manga.Format, &manga.Genres, &manga.Mappings, &manga.Popularity, &manga.Rating, &manga.Relations, &manga.Slug, &manga.Status, &manga.Synonyms, &manga.Title, &manga.TotalChapters, &manga.TotalVolumes, &manga.Type, &manga.Year)
+	err := row.Scan(&manga.ID, &manga.Artwork, &manga.AveragePopularity, &manga.AverageRating, &manga.BannerImage, &manga.Color, &manga.CountryOfOrigin, &manga.CoverImage, &manga.CurrentChapter, &manga.Description, &manga.Format, &manga.Genres, &manga.Mappings, &manga.Popularity, &manga.Rating, &manga.Relations, &manga.Slug, &manga.Status, &manga.Synonyms, &manga.Title, &manga.TotalChapters, &manga.TotalVolumes, &manga.Type, &manga.Year, &manga.Chapters, &manga.Tags, &manga.Characters, &manga.Provenance)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			println("No rows found!")
-			return &types.Manga{}, nil
-		}
 		return nil, err
 	}
 
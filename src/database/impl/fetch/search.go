@@ -0,0 +1,128 @@
+package database_fetch
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Search ranks anime or manga by how similar their titles and synonyms are to the
+// query using pg_trgm. Rows must either contain the query or pass the trigram
+// similarity threshold. Without a query the filtered rows are returned newest first.
+func Search(ctx context.Context, db database.Querier, opts database.SearchOptions) (interface{}, error) {
+	var table, columns string
+	switch opts.Type {
+	case types.TypeAnime:
+		table, columns = "anime", animeColumns
+	case types.TypeManga:
+		table, columns = "manga", mangaColumns
+	default:
+		return nil, fmt.Errorf("unknown media type: %s", opts.Type)
+	}
+
+	page := opts.Page
+	if page < 1 {
+		page = 1
+	}
+	perPage := opts.PerPage
+	if perPage < 1 {
+		perPage = 25
+	}
+
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var where []string
+	score := "0"
+
+	query := strings.TrimSpace(opts.Query)
+	if query != "" {
+		q := arg(query)
+		like := arg("%" + likeEscaper.Replace(query) + "%")
+
+		score = fmt.Sprintf(`GREATEST(
+			similarity(COALESCE(title->>'english', ''), %[1]s),
+			similarity(COALESCE(title->>'romaji', ''), %[1]s),
+			similarity(COALESCE(title->>'native', ''), %[1]s),
+			COALESCE(most_similar(%[1]s, synonyms), 0)
+		)`, q)
+
+		where = append(where, fmt.Sprintf(`(
+			title->>'english' %% %[1]s OR title->>'romaji' %% %[1]s OR title->>'native' %% %[1]s OR %[1]s %% ANY(synonyms)
+			OR title->>'english' ILIKE %[2]s OR title->>'romaji' ILIKE %[2]s OR title->>'native' ILIKE %[2]s
+			OR EXISTS (SELECT 1 FROM unnest(synonyms) s WHERE s ILIKE %[2]s)
+		)`, q, like))
+	}
+
+	if len(opts.Formats) > 0 {
+		formats := make([]string, len(opts.Formats))
+		for i, format := range opts.Formats {
+			formats[i] = string(format)
+		}
+		where = append(where, "format = ANY("+arg(formats)+")")
+	}
+	if opts.Year > 0 {
+		where = append(where, "year = "+arg(opts.Year))
+	}
+	if opts.Season != "" && opts.Season != types.SeasonUnknown && opts.Type == types.TypeAnime {
+		where = append(where, "season = "+arg(string(opts.Season)))
+	}
+	if opts.Status != "" {
+		where = append(where, "status = "+arg(string(opts.Status)))
+	}
+	if len(opts.Genres) > 0 {
+		where = append(where, "genres @> "+arg(opts.Genres))
+	}
+	if len(opts.GenresExcluded) > 0 {
+		where = append(where, "NOT (COALESCE(genres, '{}') && "+arg(opts.GenresExcluded)+")")
+	}
+	if len(opts.Tags) > 0 {
+		where = append(where, "tags @> "+arg(opts.Tags))
+	}
+	if len(opts.TagsExcluded) > 0 {
+		where = append(where, "NOT (COALESCE(tags, '{}') && "+arg(opts.TagsExcluded)+")")
+	}
+
+	sql := "SELECT " + columns + " FROM " + table
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += fmt.Sprintf(` ORDER BY %s DESC, "averagePopularity" DESC NULLS LAST, year DESC NULLS LAST LIMIT %s OFFSET %s`,
+		score, arg(perPage), arg((page-1)*perPage))
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if opts.Type == types.TypeAnime {
+		results := []types.Anime{}
+		for rows.Next() {
+			anime, err := scanAnime(rows)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, *anime)
+		}
+		return results, rows.Err()
+	}
+
+	results := []types.Manga{}
+	for rows.Next() {
+		manga, err := scanManga(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *manga)
+	}
+	return results, rows.Err()
+}
+
+// likeEscaper escapes the ILIKE wildcards so they match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
@@ -0,0 +1,163 @@
+package database_insert
+
+import (
+	"anify/eltik/go/src/database"
+	database_overrides "anify/eltik/go/src/database/impl/overrides"
+	"anify/eltik/go/src/types"
+	"context"
+	"fmt"
+)
+
+// InsertMedia inserts a media entry into the anime or manga table, or updates the
+// existing row with the same ID. The write happens inside a transaction that is
+// committed before returning. The entry's mapping overrides are applied to the
+// mappings that are written, so a write that started before an override was created
+// cannot drop a pin or bring back a blocked mapping.
+func InsertMedia(ctx context.Context, db database.Querier, media types.Media) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := database.LockMedia(ctx, tx, media.ID, media.Type); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", media.ID, err)
+	}
+
+	overrides, err := database_overrides.List(ctx, tx, media.ID, media.Type)
+	if err != nil {
+		return fmt.Errorf("failed to load the mapping overrides of %s: %w", media.ID, err)
+	}
+	media.Mappings, _ = database.ApplyMappingOverrides(media.Mappings, overrides, func(providerId string) *string {
+		// Mapping providers have the type of the entries they map.
+		providerType := string(media.Type)
+		return &providerType
+	})
+
+	switch media.Type {
+	case types.TypeAnime:
+		_, err = tx.Exec(ctx, `
+			INSERT INTO anime (
+				id, slug, "coverImage", "bannerImage", trailer, status, season, title,
+				"currentEpisode", mappings, synonyms, "countryOfOrigin", description, duration,
+				color, year, rating, popularity, type, format, relations, "totalEpisodes",
+				genres, tags, episodes, "averageRating", "averagePopularity", artwork, characters,
+				provenance
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+				$18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30
+			)
+			ON CONFLICT (id) DO UPDATE SET
+				slug = EXCLUDED.slug,
+				"coverImage" = EXCLUDED."coverImage",
+				"bannerImage" = EXCLUDED."bannerImage",
+				trailer = EXCLUDED.trailer,
+				status = EXCLUDED.status,
+				season = EXCLUDED.season,
+				title = EXCLUDED.title,
+				"currentEpisode" = EXCLUDED."currentEpisode",
+				mappings = EXCLUDED.mappings,
+				synonyms = EXCLUDED.synonyms,
+				"countryOfOrigin" = EXCLUDED."countryOfOrigin",
+				description = EXCLUDED.description,
+				duration = EXCLUDED.duration,
+				color = EXCLUDED.color,
+				year = EXCLUDED.year,
+				rating = EXCLUDED.rating,
+				popularity = EXCLUDED.popularity,
+				type = EXCLUDED.type,
+				format = EXCLUDED.format,
+				relations = EXCLUDED.relations,
+				"totalEpisodes" = EXCLUDED."totalEpisodes",
+				genres = EXCLUDED.genres,
+				tags = EXCLUDED.tags,
+				episodes = EXCLUDED.episodes,
+				"averageRating" = EXCLUDED."averageRating",
+				"averagePopularity" = EXCLUDED."averagePopularity",
+				artwork = EXCLUDED.artwork,
+				characters = EXCLUDED.characters,
+				provenance = EXCLUDED.provenance
+		`,
+			media.ID, media.Slug, media.CoverImage, media.BannerImage, media.Trailer, media.Status, media.Season, media.Title,
+			media.CurrentEpisode, nonNil(media.Mappings), nonNil(media.Synonyms), media.CountryOfOrigin, media.Description, media.Duration,
+			media.Color, media.Year, media.Rating, media.Popularity, media.Type, media.Format, nonNil(media.Relations), media.TotalEpisodes,
+			nonNil(media.Genres), nonNil(media.Tags), media.Episodes, media.AverageRating, media.AveragePopularity, nonNil(media.Artwork), nonNil(media.Characters),
+			nonNilProvenance(media.Provenance),
+		)
+	case types.TypeManga:
+		_, err = tx.Exec(ctx, `
+			INSERT INTO manga (
+				id, slug, "coverImage", "bannerImage", status, title, mappings, synonyms,
+				"countryOfOrigin", description, color, year, rating, popularity, type, format,
+				relations, "currentChapter", "totalChapters", "totalVolumes", genres, tags,
+				chapters, "averageRating", "averagePopularity", artwork, characters, provenance
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+				$18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28
+			)
+			ON CONFLICT (id) DO UPDATE SET
+				slug = EXCLUDED.slug,
+				"coverImage" = EXCLUDED."coverImage",
+				"bannerImage" = EXCLUDED."bannerImage",
+				status = EXCLUDED.status,
+				title = EXCLUDED.title,
+				mappings = EXCLUDED.mappings,
+				synonyms = EXCLUDED.synonyms,
+				"countryOfOrigin" = EXCLUDED."countryOfOrigin",
+				description = EXCLUDED.description,
+				color = EXCLUDED.color,
+				year = EXCLUDED.year,
+				rating = EXCLUDED.rating,
+				popularity = EXCLUDED.popularity,
+				type = EXCLUDED.type,
+				format = EXCLUDED.format,
+				relations = EXCLUDED.relations,
+				"currentChapter" = EXCLUDED."currentChapter",
+				"totalChapters" = EXCLUDED."totalChapters",
+				"totalVolumes" = EXCLUDED."totalVolumes",
+				genres = EXCLUDED.genres,
+				tags = EXCLUDED.tags,
+				chapters = EXCLUDED.chapters,
+				"averageRating" = EXCLUDED."averageRating",
+				"averagePopularity" = EXCLUDED."averagePopularity",
+				artwork = EXCLUDED.artwork,
+				characters = EXCLUDED.characters,
+				provenance = EXCLUDED.provenance
+		`,
+			media.ID, media.Slug, media.CoverImage, media.BannerImage, media.Status, media.Title, nonNil(media.Mappings), nonNil(media.Synonyms),
+			media.CountryOfOrigin, media.Description, media.Color, media.Year, media.Rating, media.Popularity, media.Type, media.Format,
+			nonNil(media.Relations), media.CurrentChapter, media.TotalChapters, media.TotalVolumes, nonNil(media.Genres), nonNil(media.Tags),
+			media.Chapters, media.AverageRating, media.AveragePopularity, nonNil(media.Artwork), nonNil(media.Characters),
+			nonNilProvenance(media.Provenance),
+		)
+	default:
+		return fmt.Errorf("unknown media type: %s", media.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert %s: %w", media.ID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", media.ID, err)
+	}
+
+	return nil
+}
+
+// nonNil replaces a nil slice with an empty one so array and JSONB columns are
+// stored as empty values instead of NULL.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// nonNilProvenance replaces a nil provenance with an empty one, as the column is
+// NOT NULL.
+func nonNilProvenance(p types.Provenance) types.Provenance {
+	if p == nil {
+		return types.Provenance{}
+	}
+	return p
+}
@@ -0,0 +1,353 @@
+package database_jobs
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const jobColumns = `
+	id, media_id, type, formats, state, attempts, max_attempts, last_error, run_at,
+	created_at, updated_at
+`
+
+// Enqueue adds a mapping job. If a job for the same ID and type is already queued
+// the formats are merged into it, keeping the order they were first requested in.
+// A running job that already covers every requested format is returned as is, since
+// queueing the same work behind it would only repeat it.
+func Enqueue(ctx context.Context, db database.Querier, mediaID string, type_ types.Type, formats []types.Format) (database.Job, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return database.Job{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockKey(ctx, tx, mediaID, type_); err != nil {
+		return database.Job{}, err
+	}
+
+	job, err := scanJob(tx.QueryRow(ctx, `
+		SELECT `+jobColumns+` FROM mapping_jobs
+		WHERE media_id = $1 AND type = $2 AND state = 'running' AND formats @> $3::text[]
+		ORDER BY id DESC
+		LIMIT 1
+	`, mediaID, string(type_), formatStrings(formats)))
+	if err != nil && err != pgx.ErrNoRows {
+		return database.Job{}, err
+	}
+
+	if err == pgx.ErrNoRows {
+		job, err = scanJob(tx.QueryRow(ctx, `
+			INSERT INTO mapping_jobs (media_id, type, formats)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (media_id, type) WHERE state = 'queued' DO UPDATE SET
+				formats = `+mergedFormats("mapping_jobs.formats || EXCLUDED.formats")+`,
+				updated_at = now()
+			RETURNING `+jobColumns,
+			mediaID, string(type_), formatStrings(formats),
+		))
+		if err != nil {
+			return database.Job{}, err
+		}
+	}
+
+	return job, tx.Commit(ctx)
+}
+
+// Claim locks the oldest job that is ready to run and marks it as running. Rows
+// locked by other workers are skipped, so several processes can share the queue.
+// It returns nil when no job is ready.
+func Claim(ctx context.Context, db database.Querier) (*database.Job, error) {
+	job, err := scanJob(db.QueryRow(ctx, `
+		UPDATE mapping_jobs SET
+			state = 'running',
+			attempts = attempts + 1,
+			locked_at = now(),
+			updated_at = now()
+		WHERE id = (
+			SELECT id FROM mapping_jobs
+			WHERE state = 'queued' AND run_at <= now()
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+jobColumns,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Complete marks a running job as done.
+func Complete(ctx context.Context, db database.Querier, id int64) error {
+	_, err := db.Exec(ctx, `
+		UPDATE mapping_jobs SET state = 'done', last_error = NULL, locked_at = NULL, updated_at = now()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// Fail records the error of a running job. The job is queued again at retryAt
+// unless it has used up its attempts, in which case it is marked as failed. If the
+// same ID and type was queued again while the job ran, its formats are merged into
+// that job instead and this one is marked as failed, as only one may be queued.
+func Fail(ctx context.Context, db database.Querier, id int64, jobErr error, retryAt time.Time) (database.JobState, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var mediaID, type_ string
+	if err := tx.QueryRow(ctx, `SELECT media_id, type FROM mapping_jobs WHERE id = $1`, id).Scan(&mediaID, &type_); err != nil {
+		return "", err
+	}
+	if err := lockKey(ctx, tx, mediaID, types.Type(type_)); err != nil {
+		return "", err
+	}
+
+	var formats []string
+	var attempts, maxAttempts int
+	err = tx.QueryRow(ctx, `
+		SELECT formats, attempts, max_attempts FROM mapping_jobs WHERE id = $1 FOR UPDATE
+	`, id).Scan(&formats, &attempts, &maxAttempts)
+	if err != nil {
+		return "", err
+	}
+
+	state := database.JobQueued
+	lastError := jobErr.Error()
+	if attempts >= maxAttempts {
+		state = database.JobFailed
+	} else {
+		queuedID, merged, err := mergeIntoQueued(ctx, tx, mediaID, type_, formats, id)
+		if err != nil {
+			return "", err
+		}
+		if merged {
+			state = database.JobFailed
+			lastError = fmt.Sprintf("%s (retried by job %d)", lastError, queuedID)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE mapping_jobs SET
+			state = $2,
+			last_error = $3,
+			run_at = $4,
+			locked_at = NULL,
+			updated_at = now()
+		WHERE id = $1
+	`, id, string(state), lastError, retryAt)
+	if err != nil {
+		return "", err
+	}
+
+	return state, tx.Commit(ctx)
+}
+
+// RequeueStale puts jobs that have been running for longer than olderThan back in
+// the queue. These belong to workers that stopped without finishing them. When the
+// same ID and type is already queued, or several stale jobs share it, one job is
+// queued with every format and the others are marked as failed.
+func RequeueStale(ctx context.Context, db database.Querier, olderThan time.Duration) (int64, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, media_id, type, formats FROM mapping_jobs
+		WHERE state = 'running' AND locked_at < now() - $1::interval
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED
+	`, olderThan)
+	if err != nil {
+		return 0, err
+	}
+
+	type staleJob struct {
+		ids     []int64
+		formats []string
+	}
+	var keys []string
+	stale := make(map[string]*staleJob)
+	for rows.Next() {
+		var id int64
+		var mediaID, type_ string
+		var formats []string
+		if err := rows.Scan(&id, &mediaID, &type_, &formats); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		key := type_ + "/" + mediaID
+		if stale[key] == nil {
+			keys = append(keys, key)
+			stale[key] = &staleJob{}
+		}
+		stale[key].ids = append(stale[key].ids, id)
+		stale[key].formats = append(stale[key].formats, formats...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, key := range keys {
+		type_, mediaID, _ := strings.Cut(key, "/")
+		job := stale[key]
+
+		if err := lockKey(ctx, tx, mediaID, types.Type(type_)); err != nil {
+			return 0, err
+		}
+
+		queuedID, merged, err := mergeIntoQueued(ctx, tx, mediaID, type_, job.formats, 0)
+		if err != nil {
+			return 0, err
+		}
+
+		failed := job.ids
+		if !merged {
+			queuedID, failed = job.ids[0], job.ids[1:]
+			_, err = tx.Exec(ctx, `
+				UPDATE mapping_jobs SET
+					state = 'queued',
+					formats = `+mergedFormats("$2::text[]")+`,
+					locked_at = NULL,
+					run_at = now(),
+					updated_at = now()
+				WHERE id = $1
+			`, queuedID, job.formats)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if len(failed) > 0 {
+			_, err = tx.Exec(ctx, `
+				UPDATE mapping_jobs SET
+					state = 'failed',
+					last_error = $2,
+					locked_at = NULL,
+					updated_at = now()
+				WHERE id = ANY($1)
+			`, failed, fmt.Sprintf("stale, retried by job %d", queuedID))
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		count += int64(len(job.ids))
+	}
+
+	return count, tx.Commit(ctx)
+}
+
+// List returns the most recently updated jobs, optionally only those in one state.
+func List(ctx context.Context, db database.Querier, state database.JobState, limit int) ([]database.Job, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT `+jobColumns+` FROM mapping_jobs
+		WHERE $1::text = '' OR state = $1::text
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $2
+	`, string(state), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []database.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func scanJob(row pgx.Row) (database.Job, error) {
+	var job database.Job
+	var type_ string
+	var formats []string
+
+	err := row.Scan(
+		&job.ID, &job.MediaID, &type_, &formats, &job.State, &job.Attempts, &job.MaxAttempts,
+		&job.LastError, &job.RunAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return database.Job{}, err
+	}
+
+	job.Type = types.Type(type_)
+	for _, format := range formats {
+		job.Formats = append(job.Formats, types.Format(format))
+	}
+
+	return job, nil
+}
+
+// lockKey serialises changes to the jobs of one ID and type until the transaction
+// ends, so only one of them can be queued at a time.
+func lockKey(ctx context.Context, tx pgx.Tx, mediaID string, type_ types.Type) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, string(type_), mediaID)
+	return err
+}
+
+// mergeIntoQueued adds formats to the queued job for an ID and type other than
+// exclude, if there is one, and returns its ID.
+func mergeIntoQueued(ctx context.Context, tx pgx.Tx, mediaID string, type_ string, formats []string, exclude int64) (int64, bool, error) {
+	var id int64
+	err := tx.QueryRow(ctx, `
+		UPDATE mapping_jobs SET
+			formats = `+mergedFormats("formats || $3::text[]")+`,
+			updated_at = now()
+		WHERE media_id = $1 AND type = $2 AND state = 'queued' AND id <> $4
+		RETURNING id
+	`, mediaID, type_, formats, exclude).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// mergedFormats returns SQL that removes duplicates from a text array expression,
+// keeping the order each format first appears in.
+func mergedFormats(expr string) string {
+	return `ARRAY(
+		SELECT f FROM unnest(` + expr + `) WITH ORDINALITY AS t(f, n)
+		GROUP BY f
+		ORDER BY MIN(n)
+	)`
+}
+
+func formatStrings(formats []types.Format) []string {
+	strs := make([]string, len(formats))
+	for i, format := range formats {
+		strs[i] = string(format)
+	}
+	return strs
+}
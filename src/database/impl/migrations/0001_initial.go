@@ -0,0 +1,85 @@
+package database_migrations
+
+// initial creates the anime and manga tables, the pg_trgm extension and the
+// most_similar function. Every statement is idempotent so databases created before
+// migrations existed can apply it safely. Rolling back leaves pg_trgm installed since
+// other databases on the server may use it.
+var initial = Migration{
+	Version: 1,
+	Name:    "initial",
+	Up: `
+		CREATE TABLE IF NOT EXISTS anime (
+			id TEXT PRIMARY KEY DEFAULT gen_random_uuid(),
+			slug TEXT,
+			"coverImage" TEXT,
+			"bannerImage" TEXT,
+			trailer TEXT,
+			status VARCHAR(255),
+			season VARCHAR(255) DEFAULT 'UNKNOWN',
+			title JSONB,
+			"currentEpisode" REAL,
+			mappings JSONB DEFAULT '{}'::JSONB,
+			synonyms TEXT[],
+			"countryOfOrigin" TEXT,
+			description TEXT,
+			duration REAL,
+			color TEXT,
+			year INT,
+			rating JSONB,
+			popularity JSONB,
+			type TEXT,
+			format VARCHAR(255) DEFAULT 'UNKNOWN',
+			relations JSONB[] DEFAULT '{}'::JSONB[],
+			"totalEpisodes" REAL,
+			genres TEXT[],
+			tags TEXT[],
+			episodes JSONB DEFAULT '{"latest": {"updatedAt": 0, "latestEpisode": 0, "latestTitle": ""}, "data": []}'::JSONB,
+			"averageRating" REAL,
+			"averagePopularity" REAL,
+			artwork JSONB[] DEFAULT ARRAY[]::JSONB[],
+			characters JSONB[] DEFAULT ARRAY[]::JSONB[]
+		);
+
+		CREATE TABLE IF NOT EXISTS manga (
+			id TEXT PRIMARY KEY DEFAULT gen_random_uuid(),
+			slug TEXT,
+			"coverImage" TEXT,
+			"bannerImage" TEXT,
+			status VARCHAR(255),
+			title JSONB,
+			mappings JSONB DEFAULT '{}'::JSONB,
+			synonyms TEXT[],
+			"countryOfOrigin" TEXT,
+			description TEXT,
+			color TEXT,
+			year INT,
+			rating JSONB,
+			popularity JSONB,
+			type TEXT,
+			format VARCHAR(255) DEFAULT 'UNKNOWN',
+			relations JSONB[] DEFAULT '{}'::JSONB[],
+			"currentChapter" REAL,
+			"totalChapters" REAL,
+			"totalVolumes" REAL,
+			genres TEXT[],
+			tags TEXT[],
+			chapters JSONB DEFAULT '{"latest": {"updatedAt": 0, "latestChapter": 0, "latestTitle": ""}, "data": []}'::JSONB,
+			"averageRating" REAL,
+			"averagePopularity" REAL,
+			artwork JSONB[] DEFAULT ARRAY[]::JSONB[],
+			characters JSONB[] DEFAULT ARRAY[]::JSONB[]
+		);
+
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+		CREATE OR REPLACE FUNCTION most_similar(text, text[]) RETURNS double precision
+		LANGUAGE sql AS $$
+			SELECT max(similarity($1, x)) FROM unnest($2) f(x)
+		$$;
+	`,
+	Down: `
+		DROP FUNCTION IF EXISTS most_similar(text, text[]);
+		DROP TABLE IF EXISTS manga;
+		DROP TABLE IF EXISTS anime;
+	`,
+}
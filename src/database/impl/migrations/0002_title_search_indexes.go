@@ -0,0 +1,24 @@
+package database_migrations
+
+// titleSearchIndexes adds trigram indexes on the title fields so fuzzy searches
+// do not need to scan every row.
+var titleSearchIndexes = Migration{
+	Version: 2,
+	Name:    "title_search_indexes",
+	Up: `
+		CREATE INDEX IF NOT EXISTS anime_title_english_trgm ON anime USING GIN ((title->>'english') gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS anime_title_romaji_trgm ON anime USING GIN ((title->>'romaji') gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS anime_title_native_trgm ON anime USING GIN ((title->>'native') gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS manga_title_english_trgm ON manga USING GIN ((title->>'english') gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS manga_title_romaji_trgm ON manga USING GIN ((title->>'romaji') gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS manga_title_native_trgm ON manga USING GIN ((title->>'native') gin_trgm_ops);
+	`,
+	Down: `
+		DROP INDEX IF EXISTS anime_title_english_trgm;
+		DROP INDEX IF EXISTS anime_title_romaji_trgm;
+		DROP INDEX IF EXISTS anime_title_native_trgm;
+		DROP INDEX IF EXISTS manga_title_english_trgm;
+		DROP INDEX IF EXISTS manga_title_romaji_trgm;
+		DROP INDEX IF EXISTS manga_title_native_trgm;
+	`,
+}
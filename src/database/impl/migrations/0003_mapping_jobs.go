@@ -0,0 +1,31 @@
+package database_migrations
+
+// mappingJobs adds the queue table used by the mapping workers. Only one queued job
+// may exist per ID and type so duplicate requests are merged into it.
+var mappingJobs = Migration{
+	Version: 3,
+	Name:    "mapping_jobs",
+	Up: `
+		CREATE TABLE IF NOT EXISTS mapping_jobs (
+			id BIGSERIAL PRIMARY KEY,
+			media_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			formats TEXT[] NOT NULL DEFAULT '{}',
+			state TEXT NOT NULL DEFAULT 'queued',
+			attempts INT NOT NULL DEFAULT 0,
+			max_attempts INT NOT NULL DEFAULT 5,
+			last_error TEXT,
+			run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			locked_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS mapping_jobs_queued_unique ON mapping_jobs (media_id, type) WHERE state = 'queued';
+		CREATE INDEX IF NOT EXISTS mapping_jobs_ready ON mapping_jobs (run_at, id) WHERE state = 'queued';
+		CREATE INDEX IF NOT EXISTS mapping_jobs_state ON mapping_jobs (state, updated_at);
+	`,
+	Down: `
+		DROP TABLE IF EXISTS mapping_jobs;
+	`,
+}
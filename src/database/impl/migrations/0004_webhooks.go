@@ -0,0 +1,37 @@
+package database_migrations
+
+// webhooks adds the registered webhooks and a log of every delivery attempt.
+var webhooks = Migration{
+	Version: 4,
+	Name:    "webhooks",
+	Up: `
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id BIGSERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT[] NOT NULL DEFAULT '{}',
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			webhook_id BIGINT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+			delivery_id TEXT NOT NULL,
+			event TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			attempt INT NOT NULL,
+			status INT,
+			error TEXT,
+			duration_ms INT NOT NULL DEFAULT 0,
+			success BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at DESC);
+	`,
+	Down: `
+		DROP TABLE IF EXISTS webhook_deliveries;
+		DROP TABLE IF EXISTS webhooks;
+	`,
+}
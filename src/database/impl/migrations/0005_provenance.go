@@ -0,0 +1,15 @@
+package database_migrations
+
+// provenance stores, for every anime and manga field, which providers supplied it.
+var provenance = Migration{
+	Version: 5,
+	Name:    "provenance",
+	Up: `
+		ALTER TABLE anime ADD COLUMN IF NOT EXISTS provenance JSONB NOT NULL DEFAULT '{}';
+		ALTER TABLE manga ADD COLUMN IF NOT EXISTS provenance JSONB NOT NULL DEFAULT '{}';
+	`,
+	Down: `
+		ALTER TABLE anime DROP COLUMN IF EXISTS provenance;
+		ALTER TABLE manga DROP COLUMN IF EXISTS provenance;
+	`,
+}
@@ -0,0 +1,22 @@
+package database_migrations
+
+// mappingReports keeps the match report of every run of the mapping pipeline.
+var mappingReports = Migration{
+	Version: 6,
+	Name:    "mapping_reports",
+	Up: `
+		CREATE TABLE IF NOT EXISTS mapping_reports (
+			id BIGSERIAL PRIMARY KEY,
+			media_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			outcome TEXT NOT NULL,
+			report JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE INDEX IF NOT EXISTS mapping_reports_media ON mapping_reports (media_id, created_at DESC);
+	`,
+	Down: `
+		DROP TABLE IF EXISTS mapping_reports;
+	`,
+}
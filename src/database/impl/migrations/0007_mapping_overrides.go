@@ -0,0 +1,27 @@
+package database_migrations
+
+// mappingOverrides adds manual corrections to the mapping pipeline. A provider has
+// at most one pin or "none" override per ID and type, and a pairing is blocked at
+// most once.
+var mappingOverrides = Migration{
+	Version: 7,
+	Name:    "mapping_overrides",
+	Up: `
+		CREATE TABLE IF NOT EXISTS mapping_overrides (
+			id BIGSERIAL PRIMARY KEY,
+			media_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			provider_id TEXT NOT NULL,
+			provider_media_id TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS mapping_overrides_provider ON mapping_overrides (media_id, type, provider_id) WHERE kind IN ('pin', 'none');
+		CREATE UNIQUE INDEX IF NOT EXISTS mapping_overrides_block ON mapping_overrides (media_id, type, provider_id, provider_media_id) WHERE kind = 'block';
+	`,
+	Down: `
+		DROP TABLE IF EXISTS mapping_overrides;
+	`,
+}
@@ -0,0 +1,217 @@
+package database_migrations
+
+import (
+	"anify/eltik/go/src/database"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Migration is a numbered schema change. Up applies it and Down reverts it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// migrations lists every migration. Versions must be unique and are applied in order.
+var migrations = []Migration{
+	initial,
+	titleSearchIndexes,
+	mappingJobs,
+	webhooks,
+	provenance,
+	mappingReports,
+	mappingOverrides,
+}
+
+// lockID is the advisory lock key held while a migration runs so that several
+// processes starting at once do not apply the same migration twice.
+const lockID = 7_275_621_001
+
+// Migrations returns every known migration sorted by version.
+func Migrations() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
+
+// Up applies every pending migration up to and including target. A target of 0
+// applies all of them. It returns the migrations that were applied.
+func Up(ctx context.Context, db database.Querier, target int) ([]Migration, error) {
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range Migrations() {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		ran, err := run(ctx, db, m, true)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, m)
+		}
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first. It returns the
+// migrations that were rolled back.
+func Down(ctx context.Context, db database.Querier, steps int) ([]Migration, error) {
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	all := Migrations()
+
+	var done []Migration
+	for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+		m := all[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		ran, err := run(ctx, db, m, false)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, m)
+		}
+	}
+
+	return done, nil
+}
+
+// GetStatus returns every known migration and whether it has been applied.
+func GetStatus(ctx context.Context, db database.Querier) ([]Status, error) {
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range Migrations() {
+		status := Status{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func ensureTable(ctx context.Context, db database.Querier) error {
+	_, err := db.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("unable to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, db database.Querier) (map[int]time.Time, error) {
+	rows, err := db.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// run applies or reverts a single migration in its own transaction. It returns
+// false when another process already did the work while we waited for the lock.
+func run(ctx context.Context, db database.Querier, m Migration, up bool) (bool, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		return false, fmt.Errorf("unable to acquire migration lock: %w", err)
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&exists)
+	if err != nil && err != pgx.ErrNoRows {
+		return false, err
+	}
+	if exists == up {
+		return false, nil
+	}
+
+	if up {
+		if _, err := tx.Exec(ctx, m.Up); err != nil {
+			return false, fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return false, err
+		}
+	} else {
+		if _, err := tx.Exec(ctx, m.Down); err != nil {
+			return false, fmt.Errorf("rollback of %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
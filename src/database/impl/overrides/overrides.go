@@ -0,0 +1,100 @@
+package database_overrides
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const overrideColumns = `id, media_id, type, provider_id, provider_media_id, kind, note, created_at`
+
+// Set stores an override. A pin or "none" replaces the provider's existing pin or
+// "none" for the ID, and blocking an already blocked pairing only updates the note.
+// It waits for writes of the entry in progress, which apply the overrides they saw,
+// so the override can be applied to the stored entry once Set returns.
+func Set(ctx context.Context, db database.Querier, override database.MappingOverride) (database.MappingOverride, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return database.MappingOverride{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := database.LockMedia(ctx, tx, override.MediaID, override.Type); err != nil {
+		return database.MappingOverride{}, err
+	}
+
+	conflict := `(media_id, type, provider_id) WHERE kind IN ('pin', 'none') DO UPDATE SET
+		provider_media_id = EXCLUDED.provider_media_id,
+		kind = EXCLUDED.kind,
+		note = EXCLUDED.note,
+		created_at = now()`
+	if override.Kind == database.OverrideBlock {
+		conflict = `(media_id, type, provider_id, provider_media_id) WHERE kind = 'block' DO UPDATE SET
+		note = EXCLUDED.note`
+	}
+
+	stored, err := scanOverride(tx.QueryRow(ctx, `
+		INSERT INTO mapping_overrides (media_id, type, provider_id, provider_media_id, kind, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT `+conflict+`
+		RETURNING `+overrideColumns,
+		override.MediaID, override.Type, override.ProviderID, override.ProviderMediaID, override.Kind, override.Note,
+	))
+	if err != nil {
+		return database.MappingOverride{}, err
+	}
+
+	return stored, tx.Commit(ctx)
+}
+
+// List returns the overrides of an ID, or every override when mediaID is empty. An
+// empty type matches either type.
+func List(ctx context.Context, db database.Querier, mediaID string, type_ types.Type) ([]database.MappingOverride, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+overrideColumns+` FROM mapping_overrides
+		WHERE ($1::text = '' OR media_id = $1::text) AND ($2::text = '' OR type = $2::text)
+		ORDER BY media_id, provider_id, id
+	`, mediaID, string(type_))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []database.MappingOverride{}
+	for rows.Next() {
+		override, err := scanOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+
+	return overrides, rows.Err()
+}
+
+// Delete removes an override and returns it. It returns nil when none existed.
+func Delete(ctx context.Context, db database.Querier, id int64) (*database.MappingOverride, error) {
+	override, err := scanOverride(db.QueryRow(ctx, `DELETE FROM mapping_overrides WHERE id = $1 RETURNING `+overrideColumns, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &override, nil
+}
+
+func scanOverride(row pgx.Row) (database.MappingOverride, error) {
+	var override database.MappingOverride
+
+	err := row.Scan(&override.ID, &override.MediaID, &override.Type, &override.ProviderID, &override.ProviderMediaID, &override.Kind, &override.Note, &override.CreatedAt)
+	if err != nil {
+		return database.MappingOverride{}, err
+	}
+
+	return override, nil
+}
@@ -0,0 +1,39 @@
+package database_reports
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Save stores a match report.
+func Save(ctx context.Context, db database.Querier, report types.MatchReport) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO mapping_reports (media_id, type, outcome, report, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, report.MediaID, report.Type, report.Outcome, report, report.CreatedAt)
+	return err
+}
+
+// Latest fetches the most recent match report of an ID. An empty type matches
+// either type. It returns nil when the ID was never mapped.
+func Latest(ctx context.Context, db database.Querier, mediaID string, type_ types.Type) (*types.MatchReport, error) {
+	var report types.MatchReport
+
+	err := db.QueryRow(ctx, `
+		SELECT report FROM mapping_reports
+		WHERE media_id = $1 AND ($2::text = '' OR type = $2::text)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, mediaID, string(type_)).Scan(&report)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,115 @@
+package database_repository
+
+import (
+	"anify/eltik/go/src/database"
+	database_fetch "anify/eltik/go/src/database/impl/fetch"
+	database_insert "anify/eltik/go/src/database/impl/insert"
+	database_jobs "anify/eltik/go/src/database/impl/jobs"
+	database_overrides "anify/eltik/go/src/database/impl/overrides"
+	database_reports "anify/eltik/go/src/database/impl/reports"
+	database_webhooks "anify/eltik/go/src/database/impl/webhooks"
+	"anify/eltik/go/src/types"
+	"context"
+	"time"
+)
+
+// PostgresRepository implements database.Repository on top of a pool or transaction.
+type PostgresRepository struct {
+	db database.Querier
+}
+
+func New(db database.Querier) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, id string, type_ types.Type) (interface{}, error) {
+	return database_fetch.Get(ctx, r.db, id, type_)
+}
+
+func (r *PostgresRepository) GetAnimeByID(ctx context.Context, id string) (*types.Anime, error) {
+	return database_fetch.GetAnimeByID(ctx, r.db, id)
+}
+
+func (r *PostgresRepository) GetMangaByID(ctx context.Context, id string) (*types.Manga, error) {
+	return database_fetch.GetMangaByID(ctx, r.db, id)
+}
+
+func (r *PostgresRepository) Search(ctx context.Context, opts database.SearchOptions) (interface{}, error) {
+	return database_fetch.Search(ctx, r.db, opts)
+}
+
+func (r *PostgresRepository) ExistingIDs(ctx context.Context, type_ types.Type, ids []string) (map[string]bool, error) {
+	return database_fetch.ExistingIDs(ctx, r.db, type_, ids)
+}
+
+func (r *PostgresRepository) InsertMedia(ctx context.Context, media types.Media) error {
+	return database_insert.InsertMedia(ctx, r.db, media)
+}
+
+func (r *PostgresRepository) EnqueueJob(ctx context.Context, mediaID string, type_ types.Type, formats []types.Format) (database.Job, error) {
+	return database_jobs.Enqueue(ctx, r.db, mediaID, type_, formats)
+}
+
+func (r *PostgresRepository) ClaimJob(ctx context.Context) (*database.Job, error) {
+	return database_jobs.Claim(ctx, r.db)
+}
+
+func (r *PostgresRepository) CompleteJob(ctx context.Context, id int64) error {
+	return database_jobs.Complete(ctx, r.db, id)
+}
+
+func (r *PostgresRepository) FailJob(ctx context.Context, id int64, jobErr error, retryAt time.Time) (database.JobState, error) {
+	return database_jobs.Fail(ctx, r.db, id, jobErr, retryAt)
+}
+
+func (r *PostgresRepository) RequeueStaleJobs(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return database_jobs.RequeueStale(ctx, r.db, olderThan)
+}
+
+func (r *PostgresRepository) ListJobs(ctx context.Context, state database.JobState, limit int) ([]database.Job, error) {
+	return database_jobs.List(ctx, r.db, state, limit)
+}
+
+func (r *PostgresRepository) CreateWebhook(ctx context.Context, webhook database.Webhook) (database.Webhook, error) {
+	return database_webhooks.Create(ctx, r.db, webhook)
+}
+
+func (r *PostgresRepository) GetWebhook(ctx context.Context, id int64) (*database.Webhook, error) {
+	return database_webhooks.Get(ctx, r.db, id)
+}
+
+func (r *PostgresRepository) ListWebhooks(ctx context.Context, event string) ([]database.Webhook, error) {
+	return database_webhooks.List(ctx, r.db, event)
+}
+
+func (r *PostgresRepository) DeleteWebhook(ctx context.Context, id int64) (bool, error) {
+	return database_webhooks.Delete(ctx, r.db, id)
+}
+
+func (r *PostgresRepository) LogWebhookDelivery(ctx context.Context, delivery database.WebhookDelivery) error {
+	return database_webhooks.LogDelivery(ctx, r.db, delivery)
+}
+
+func (r *PostgresRepository) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]database.WebhookDelivery, error) {
+	return database_webhooks.ListDeliveries(ctx, r.db, webhookID, limit)
+}
+
+func (r *PostgresRepository) SaveMatchReport(ctx context.Context, report types.MatchReport) error {
+	return database_reports.Save(ctx, r.db, report)
+}
+
+func (r *PostgresRepository) GetMatchReport(ctx context.Context, mediaID string, type_ types.Type) (*types.MatchReport, error) {
+	return database_reports.Latest(ctx, r.db, mediaID, type_)
+}
+
+func (r *PostgresRepository) SetMappingOverride(ctx context.Context, override database.MappingOverride) (database.MappingOverride, error) {
+	return database_overrides.Set(ctx, r.db, override)
+}
+
+func (r *PostgresRepository) ListMappingOverrides(ctx context.Context, mediaID string, type_ types.Type) ([]database.MappingOverride, error) {
+	return database_overrides.List(ctx, r.db, mediaID, type_)
+}
+
+func (r *PostgresRepository) DeleteMappingOverride(ctx context.Context, id int64) (*database.MappingOverride, error) {
+	return database_overrides.Delete(ctx, r.db, id)
+}
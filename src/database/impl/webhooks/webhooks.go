@@ -0,0 +1,139 @@
+package database_webhooks
+
+import (
+	"anify/eltik/go/src/database"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const webhookColumns = `id, url, secret, events, active, created_at`
+
+const deliveryColumns = `
+	id, webhook_id, delivery_id, event, payload, attempt, status, error, duration_ms,
+	success, created_at
+`
+
+// Create registers a webhook.
+func Create(ctx context.Context, db database.Querier, webhook database.Webhook) (database.Webhook, error) {
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	return scanWebhook(db.QueryRow(ctx, `
+		INSERT INTO webhooks (url, secret, events, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+webhookColumns,
+		webhook.URL, webhook.Secret, webhook.Events, webhook.Active,
+	))
+}
+
+// Get fetches a webhook by its ID. It returns nil when nothing is found.
+func Get(ctx context.Context, db database.Querier, id int64) (*database.Webhook, error) {
+	webhook, err := scanWebhook(db.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// List returns every webhook. With activeFor set, only the active webhooks that
+// subscribe to that event are returned.
+func List(ctx context.Context, db database.Querier, activeFor string) ([]database.Webhook, error) {
+	rows, err := db.Query(ctx, `
+		SELECT `+webhookColumns+` FROM webhooks
+		WHERE $1::text = '' OR (active AND (cardinality(events) = 0 OR $1::text = ANY(events)))
+		ORDER BY id
+	`, activeFor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []database.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Delete removes a webhook and its delivery log. It reports whether it existed.
+func Delete(ctx context.Context, db database.Querier, id int64) (bool, error) {
+	tag, err := db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// LogDelivery records one delivery attempt.
+func LogDelivery(ctx context.Context, db database.Querier, delivery database.WebhookDelivery) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (
+			webhook_id, delivery_id, event, payload, attempt, status, error, duration_ms, success
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		delivery.WebhookID, delivery.DeliveryID, delivery.Event, string(delivery.Payload), delivery.Attempt,
+		delivery.Status, delivery.Error, delivery.DurationMs, delivery.Success,
+	)
+	return err
+}
+
+// ListDeliveries returns the most recent delivery attempts of a webhook.
+func ListDeliveries(ctx context.Context, db database.Querier, webhookID int64, limit int) ([]database.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []database.WebhookDelivery{}
+	for rows.Next() {
+		var delivery database.WebhookDelivery
+		var payload string
+
+		err := rows.Scan(
+			&delivery.ID, &delivery.WebhookID, &delivery.DeliveryID, &delivery.Event, &payload,
+			&delivery.Attempt, &delivery.Status, &delivery.Error, &delivery.DurationMs,
+			&delivery.Success, &delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.Payload = []byte(payload)
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row pgx.Row) (database.Webhook, error) {
+	var webhook database.Webhook
+
+	err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.Events, &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		return database.Webhook{}, err
+	}
+
+	return webhook, nil
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"anify/eltik/go/src/types"
+	"context"
+	"time"
+)
+
+// Repository is the storage API used by the rest of the application. Packages should
+// depend on it instead of the global DB.
+type Repository interface {
+	// Get fetches an anime or manga by its ID. It returns nil when nothing is found.
+	Get(ctx context.Context, id string, type_ types.Type) (interface{}, error)
+	GetAnimeByID(ctx context.Context, id string) (*types.Anime, error)
+	GetMangaByID(ctx context.Context, id string) (*types.Manga, error)
+	// Search ranks stored anime or manga by title similarity. It returns []types.Anime
+	// or []types.Manga depending on opts.Type.
+	Search(ctx context.Context, opts SearchOptions) (interface{}, error)
+	// ExistingIDs returns which of the IDs are already stored for the type.
+	ExistingIDs(ctx context.Context, type_ types.Type, ids []string) (map[string]bool, error)
+	// InsertMedia inserts a media entry or updates the existing row with the same ID.
+	// The entry's mapping overrides are applied to the mappings that are written.
+	InsertMedia(ctx context.Context, media types.Media) error
+
+	// EnqueueJob queues the mapping pipeline for an ID, merging it into an already
+	// queued job for the same ID and type. A running job that covers every format
+	// is returned instead.
+	EnqueueJob(ctx context.Context, mediaID string, type_ types.Type, formats []types.Format) (Job, error)
+	// ClaimJob marks the next ready job as running. It returns nil when none is ready.
+	ClaimJob(ctx context.Context) (*Job, error)
+	CompleteJob(ctx context.Context, id int64) error
+	// FailJob records a failed attempt and returns the job's new state.
+	FailJob(ctx context.Context, id int64, jobErr error, retryAt time.Time) (JobState, error)
+	RequeueStaleJobs(ctx context.Context, olderThan time.Duration) (int64, error)
+	// ListJobs returns recently updated jobs. An empty state lists every job.
+	ListJobs(ctx context.Context, state JobState, limit int) ([]Job, error)
+
+	CreateWebhook(ctx context.Context, webhook Webhook) (Webhook, error)
+	// GetWebhook fetches a webhook by its ID. It returns nil when nothing is found.
+	GetWebhook(ctx context.Context, id int64) (*Webhook, error)
+	// ListWebhooks returns every webhook, or with event set only the active ones
+	// subscribed to it.
+	ListWebhooks(ctx context.Context, event string) ([]Webhook, error)
+	// DeleteWebhook removes a webhook and reports whether it existed.
+	DeleteWebhook(ctx context.Context, id int64) (bool, error)
+	LogWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]WebhookDelivery, error)
+
+	SaveMatchReport(ctx context.Context, report types.MatchReport) error
+	// GetMatchReport fetches the most recent match report of an ID. An empty type
+	// matches either type. It returns nil when the ID was never mapped.
+	GetMatchReport(ctx context.Context, mediaID string, type_ types.Type) (*types.MatchReport, error)
+
+	// SetMappingOverride stores an override. A pin or "none" replaces the existing
+	// pin or "none" of the same provider.
+	SetMappingOverride(ctx context.Context, override MappingOverride) (MappingOverride, error)
+	// ListMappingOverrides returns the overrides of an ID, or every override when
+	// mediaID is empty. An empty type matches either type.
+	ListMappingOverrides(ctx context.Context, mediaID string, type_ types.Type) ([]MappingOverride, error)
+	// DeleteMappingOverride removes an override. It returns nil when none existed.
+	DeleteMappingOverride(ctx context.Context, id int64) (*MappingOverride, error)
+}
+
+// SearchOptions filters a database search. Zero values mean "any".
+type SearchOptions struct {
+	Query          string
+	Type           types.Type
+	Formats        []types.Format
+	Year           int
+	Season         types.Season
+	Status         types.Status
+	Genres         []string
+	GenresExcluded []string
+	Tags           []string
+	TagsExcluded   []string
+	Page           int
+	PerPage        int
+}
+
+// JobState is where a mapping job is in its lifecycle.
+type JobState string
+
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+)
+
+// Job is a queued run of the mapping pipeline for one ID.
+type Job struct {
+	ID          int64          `json:"id"`
+	MediaID     string         `json:"mediaId"`
+	Type        types.Type     `json:"type"`
+	Formats     []types.Format `json:"formats"`
+	State       JobState       `json:"state"`
+	Attempts    int            `json:"attempts"`
+	MaxAttempts int            `json:"maxAttempts"`
+	LastError   *string        `json:"lastError"`
+	RunAt       time.Time      `json:"runAt"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// Webhook is a URL that receives events as signed JSON POST requests.
+type Webhook struct {
+	ID     int64  `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	// Events lists the topics sent to the webhook. Empty means every topic.
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookDelivery is one attempt at delivering an event to a webhook.
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  int64     `json:"webhookId"`
+	DeliveryID string    `json:"deliveryId"`
+	Event      string    `json:"event"`
+	Payload    []byte    `json:"-"`
+	Attempt    int       `json:"attempt"`
+	Status     *int      `json:"status"`
+	Error      *string   `json:"error"`
+	DurationMs int       `json:"durationMs"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// OverrideKind is what a mapping override does.
+type OverrideKind string
+
+const (
+	// OverridePin maps the provider to ProviderMediaID without fuzzy matching.
+	OverridePin OverrideKind = "pin"
+	// OverrideBlock stops ProviderMediaID from being mapped by fuzzy matching.
+	OverrideBlock OverrideKind = "block"
+	// OverrideNone marks the provider as having no match, so it is not searched.
+	OverrideNone OverrideKind = "none"
+)
+
+// MappingOverride is a manual correction of the mappings of one ID.
+type MappingOverride struct {
+	ID         int64      `json:"id"`
+	MediaID    string     `json:"mediaId"`
+	Type       types.Type `json:"type"`
+	ProviderID string     `json:"providerId"`
+	// ProviderMediaID is the provider's ID for pins and blocks, empty for "none".
+	ProviderMediaID string       `json:"providerMediaId"`
+	Kind            OverrideKind `json:"kind"`
+	Note            string       `json:"note"`
+	CreatedAt       time.Time    `json:"createdAt"`
+}
+
+// ApplyMappingOverrides returns the mappings with the overrides of their entry
+// applied: pinned providers are mapped to the pinned ID, and mappings of providers
+// marked as having no match or of blocked pairings are removed. Pins that add a
+// mapping take their provider type from providerType. It also reports whether the
+// mappings changed.
+func ApplyMappingOverrides(mappings []types.Mapping, overrides []MappingOverride, providerType func(providerId string) *string) ([]types.Mapping, bool) {
+	pins := make(map[string]string)
+	none := make(map[string]bool)
+	blocked := make(map[string]bool)
+	for _, override := range overrides {
+		switch override.Kind {
+		case OverridePin:
+			pins[override.ProviderID] = override.ProviderMediaID
+		case OverrideNone:
+			none[override.ProviderID] = true
+		case OverrideBlock:
+			blocked[override.ProviderID+"/"+override.ProviderMediaID] = true
+		}
+	}
+
+	changed := false
+	pinned := make(map[string]bool)
+	applied := make([]types.Mapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		pin, ok := pins[mapping.ProviderID]
+		if ok && pin == mapping.ID && !pinned[mapping.ProviderID] {
+			pinned[mapping.ProviderID] = true
+		} else if ok || none[mapping.ProviderID] || blocked[mapping.ProviderID+"/"+mapping.ID] {
+			changed = true
+			continue
+		}
+		applied = append(applied, mapping)
+	}
+
+	for _, override := range overrides {
+		if override.Kind != OverridePin || pinned[override.ProviderID] || pins[override.ProviderID] != override.ProviderMediaID {
+			continue
+		}
+		pinned[override.ProviderID] = true
+		applied = append(applied, types.Mapping{
+			ID:           override.ProviderMediaID,
+			ProviderID:   override.ProviderID,
+			Similarity:   1,
+			ProviderType: providerType(override.ProviderID),
+		})
+		changed = true
+	}
+
+	return applied, changed
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"anify/eltik/go/src/types"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMappingOverrides(t *testing.T) {
+	providerType := func(providerId string) *string {
+		providerType := "MANGA"
+		return &providerType
+	}
+
+	tests := []struct {
+		name      string
+		mappings  []types.Mapping
+		overrides []MappingOverride
+		want      []string
+		changed   bool
+	}{
+		{
+			name:     "no overrides",
+			mappings: []types.Mapping{{ID: "1", ProviderID: "a"}},
+			want:     []string{"a/1"},
+		},
+		{
+			name:      "pin replaces a stale mapping",
+			mappings:  []types.Mapping{{ID: "stale", ProviderID: "a"}, {ID: "2", ProviderID: "b"}},
+			overrides: []MappingOverride{{ProviderID: "a", ProviderMediaID: "1", Kind: OverridePin}},
+			want:      []string{"b/2", "a/1"},
+			changed:   true,
+		},
+		{
+			name:      "pin already mapped",
+			mappings:  []types.Mapping{{ID: "1", ProviderID: "a"}},
+			overrides: []MappingOverride{{ProviderID: "a", ProviderMediaID: "1", Kind: OverridePin}},
+			want:      []string{"a/1"},
+		},
+		{
+			name:      "pin adds a missing mapping",
+			overrides: []MappingOverride{{ProviderID: "a", ProviderMediaID: "1", Kind: OverridePin}},
+			want:      []string{"a/1"},
+			changed:   true,
+		},
+		{
+			name:      "none removes the provider",
+			mappings:  []types.Mapping{{ID: "1", ProviderID: "a"}, {ID: "2", ProviderID: "b"}},
+			overrides: []MappingOverride{{ProviderID: "a", Kind: OverrideNone}},
+			want:      []string{"b/2"},
+			changed:   true,
+		},
+		{
+			name:      "block removes only the pairing",
+			mappings:  []types.Mapping{{ID: "1", ProviderID: "a"}, {ID: "2", ProviderID: "a"}},
+			overrides: []MappingOverride{{ProviderID: "a", ProviderMediaID: "1", Kind: OverrideBlock}},
+			want:      []string{"a/2"},
+			changed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, changed := ApplyMappingOverrides(tt.mappings, tt.overrides, providerType)
+
+			got := []string{}
+			for _, mapping := range mappings {
+				got = append(got, mapping.ProviderID+"/"+mapping.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) || changed != tt.changed {
+				t.Errorf("ApplyMappingOverrides = %v, %v, want %v, %v", got, changed, tt.want, tt.changed)
+			}
+
+			for _, mapping := range mappings {
+				if mapping.ID == "1" && tt.changed && len(tt.overrides) > 0 && tt.overrides[0].Kind == OverridePin {
+					if mapping.Similarity != 1 || mapping.ProviderType == nil || *mapping.ProviderType != "MANGA" {
+						t.Errorf("pinned mapping = %+v", mapping)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,265 @@
 package events
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
 	"github.com/asaskevich/EventBus"
 )
 
 const (
 	COMPLETED_MAPPING_LOAD   = "mapping.load.completed"
+	FAILED_MAPPING_LOAD      = "mapping.load.failed"
 	COMPLETED_SEARCH_LOAD    = "search.load.completed"
 	COMPLETED_SEASONAL_LOAD  = "seasonal.load.completed"
 	COMPLETED_ENTRY_CREATION = "entry.creation.completed"
+	UPDATED_CHAPTERS         = "chapters.updated"
+	BANNED_PROXY             = "proxy.banned"
 )
 
+// Topics lists every topic published on the bus.
+var Topics = []string{
+	COMPLETED_MAPPING_LOAD,
+	FAILED_MAPPING_LOAD,
+	COMPLETED_SEARCH_LOAD,
+	COMPLETED_SEASONAL_LOAD,
+	COMPLETED_ENTRY_CREATION,
+	UPDATED_CHAPTERS,
+	BANNED_PROXY,
+}
+
 var Bus = EventBus.New()
+
+// Event is a payload published on the bus. Topic returns the topic it is published
+// under, which must be one of Topics.
+type Event interface {
+	Topic() string
+}
+
+// ProviderMapping is a provider entry that was matched to a media ID.
+type ProviderMapping struct {
+	ProviderID string  `json:"providerId"`
+	ID         string  `json:"id"`
+	Similarity float64 `json:"similarity"`
+}
+
+// MappingLoadCompleted is published when LoadMappings finishes for an ID, whether
+// it created entries, found an existing one or found nothing to map.
+type MappingLoadCompleted struct {
+	MediaID  string            `json:"mediaId"`
+	Type     string            `json:"type"`
+	Mappings []ProviderMapping `json:"mappings"`
+	// Existing is true when the entry was already stored and nothing was fetched.
+	Existing   bool  `json:"existing"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// MappingLoadFailed is published when LoadMappings returns an error.
+type MappingLoadFailed struct {
+	MediaID    string `json:"mediaId"`
+	Type       string `json:"type"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error"`
+}
+
+// EntryCreated is published after a new or updated entry has been committed.
+type EntryCreated struct {
+	MediaID  string            `json:"mediaId"`
+	Type     string            `json:"type"`
+	Slug     string            `json:"slug"`
+	Mappings []ProviderMapping `json:"mappings"`
+}
+
+// SearchLoadCompleted is published when a search had to be answered by a provider.
+type SearchLoadCompleted struct {
+	ProviderID string `json:"providerId"`
+	Query      string `json:"query"`
+	Type       string `json:"type"`
+	Results    int    `json:"results"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SeasonalLoadCompleted is published when the seasonal lists were fetched.
+type SeasonalLoadCompleted struct {
+	ProviderID string `json:"providerId"`
+	Type       string `json:"type"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ChaptersUpdated is published when a provider has chapters past the latest one
+// stored for a manga.
+type ChaptersUpdated struct {
+	MediaID     string  `json:"mediaId"`
+	ProviderID  string  `json:"providerId"`
+	Previous    float64 `json:"previous"`
+	Latest      float64 `json:"latest"`
+	LatestTitle string  `json:"latestTitle"`
+	// New is how many chapters are numbered after Previous.
+	New int `json:"new"`
+}
+
+// ProxyBanned is published when a proxy is banned for a provider.
+type ProxyBanned struct {
+	ProviderID string    `json:"providerId"`
+	Proxy      string    `json:"proxy"`
+	Failures   int       `json:"failures"`
+	Until      time.Time `json:"until"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (MappingLoadCompleted) Topic() string  { return COMPLETED_MAPPING_LOAD }
+func (MappingLoadFailed) Topic() string     { return FAILED_MAPPING_LOAD }
+func (EntryCreated) Topic() string          { return COMPLETED_ENTRY_CREATION }
+func (SearchLoadCompleted) Topic() string   { return COMPLETED_SEARCH_LOAD }
+func (SeasonalLoadCompleted) Topic() string { return COMPLETED_SEASONAL_LOAD }
+func (ChaptersUpdated) Topic() string       { return UPDATED_CHAPTERS }
+func (ProxyBanned) Topic() string           { return BANNED_PROXY }
+
+// MediaType returns the media type an event is about, or "" when it has none.
+func MediaType(event Event) string {
+	switch e := event.(type) {
+	case MappingLoadCompleted:
+		return e.Type
+	case MappingLoadFailed:
+		return e.Type
+	case EntryCreated:
+		return e.Type
+	case SearchLoadCompleted:
+		return e.Type
+	case SeasonalLoadCompleted:
+		return e.Type
+	case ChaptersUpdated:
+		return "MANGA"
+	}
+	return ""
+}
+
+// ProviderIDs returns the providers an event involves.
+func ProviderIDs(event Event) []string {
+	fromMappings := func(mappings []ProviderMapping) []string {
+		ids := make([]string, 0, len(mappings))
+		for _, mapping := range mappings {
+			ids = append(ids, mapping.ProviderID)
+		}
+		return ids
+	}
+
+	switch e := event.(type) {
+	case MappingLoadCompleted:
+		return fromMappings(e.Mappings)
+	case EntryCreated:
+		return fromMappings(e.Mappings)
+	case SearchLoadCompleted:
+		return []string{e.ProviderID}
+	case SeasonalLoadCompleted:
+		return []string{e.ProviderID}
+	case ChaptersUpdated:
+		return []string{e.ProviderID}
+	case ProxyBanned:
+		return []string{e.ProviderID}
+	}
+	return nil
+}
+
+// Envelope wraps an event for delivery outside of the process, such as to webhooks.
+type Envelope struct {
+	// ID is unique per envelope so receivers can drop duplicate deliveries.
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      Event     `json:"data"`
+}
+
+// NewEnvelope wraps an event with a new ID and the current time.
+func NewEnvelope(event Event) Envelope {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return Envelope{
+		ID:        hex.EncodeToString(id),
+		Event:     event.Topic(),
+		CreatedAt: time.Now().UTC(),
+		Data:      event,
+	}
+}
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   = make(map[string]map[int]func(Event))
+	nextID        int
+)
+
+// Every topic is relayed from Bus to the typed subscribers, so string subscribers
+// on Bus and typed ones receive the same events.
+func init() {
+	for _, topic := range Topics {
+		topic := topic
+		Bus.Subscribe(topic, func(event Event) {
+			dispatch(topic, event)
+		})
+	}
+}
+
+// Publish sends an event to every subscriber of its topic. Handlers run on the
+// publishing goroutine, so slow ones should hand the work off.
+func Publish[T Event](event T) {
+	Bus.Publish(event.Topic(), event)
+}
+
+// Subscribe calls handler for every event of type T. The returned function removes
+// the subscription.
+func Subscribe[T Event](handler func(T)) func() {
+	var zero T
+	return subscribe([]string{zero.Topic()}, func(event Event) {
+		if e, ok := event.(T); ok {
+			handler(e)
+		}
+	})
+}
+
+// SubscribeAll calls handler for every event on every topic. The returned function
+// removes the subscription.
+func SubscribeAll(handler func(Event)) func() {
+	return subscribe(Topics, handler)
+}
+
+func subscribe(topics []string, handler func(Event)) func() {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	nextID++
+	id := nextID
+	for _, topic := range topics {
+		if subscribers[topic] == nil {
+			subscribers[topic] = make(map[int]func(Event))
+		}
+		subscribers[topic][id] = handler
+	}
+
+	return func() {
+		subscribersMu.Lock()
+		defer subscribersMu.Unlock()
+
+		for _, topic := range topics {
+			delete(subscribers[topic], id)
+		}
+	}
+}
+
+func dispatch(topic string, event Event) {
+	subscribersMu.RLock()
+	handlers := make([]func(Event), 0, len(subscribers[topic]))
+	for _, handler := range subscribers[topic] {
+		handlers = append(handlers, handler)
+	}
+	subscribersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
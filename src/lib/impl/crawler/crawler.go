@@ -0,0 +1,213 @@
+package crawler
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/lib/impl/mappings"
+	"anify/eltik/go/src/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// batchSize is how many IDs are checked against the database per query.
+const batchSize = 1000
+
+// Options controls a crawl.
+type Options struct {
+	Type    types.Type
+	Formats []types.Format
+	// CheckpointPath is where progress is saved so an interrupted crawl resumes.
+	CheckpointPath string
+	// DryRun only reports what would be crawled.
+	DryRun bool
+	// Queue adds the IDs to the mapping queue instead of mapping them here.
+	Queue func(ctx context.Context, id string) error
+}
+
+// Checkpoint is the saved progress of a crawl. IDs are crawled in sorted order, so
+// everything up to and including LastID is done.
+type Checkpoint struct {
+	Type      string    `json:"type"`
+	LastID    string    `json:"lastId"`
+	Done      int       `json:"done"`
+	Failed    int       `json:"failed"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Result summarises a crawl.
+type Result struct {
+	Total    int
+	Existing int
+	Resumed  int
+	Pending  []string
+	Done     int
+	Failed   int
+}
+
+// Crawl maps every ID of a base provider that is not stored yet. It blocks until all
+// of them are done or the context is cancelled, in which case the checkpoint keeps
+// the progress so far.
+func Crawl(ctx context.Context, repo database.Repository, provider types.BaseProvider, opts Options) (Result, error) {
+	ids, err := provider.GetIds()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to fetch IDs: %w", err)
+	}
+	sort.Strings(ids)
+
+	checkpoint, err := loadCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		return Result{}, err
+	}
+	if checkpoint.Type != string(opts.Type) {
+		checkpoint = Checkpoint{Type: string(opts.Type)}
+	}
+
+	result := Result{Total: len(ids), Done: checkpoint.Done, Failed: checkpoint.Failed}
+
+	var remaining []string
+	for _, id := range ids {
+		if checkpoint.LastID != "" && id <= checkpoint.LastID {
+			result.Resumed++
+			continue
+		}
+		remaining = append(remaining, id)
+	}
+
+	for start := 0; start < len(remaining); start += batchSize {
+		end := min(start+batchSize, len(remaining))
+		batch := remaining[start:end]
+
+		existing, err := repo.ExistingIDs(ctx, opts.Type, batch)
+		if err != nil {
+			return result, fmt.Errorf("failed to check existing IDs: %w", err)
+		}
+
+		for _, id := range batch {
+			if existing[id] {
+				result.Existing++
+				continue
+			}
+			result.Pending = append(result.Pending, id)
+		}
+	}
+
+	fmt.Printf("%d IDs: %d already stored, %d done in a previous run, %d to crawl\n", result.Total, result.Existing, result.Resumed, len(result.Pending))
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	started := time.Now()
+	for i, id := range result.Pending {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		if err := crawlOne(ctx, repo, id, opts); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			log.Printf("Failed to crawl %s: %v", id, err)
+			result.Failed++
+		} else {
+			result.Done++
+		}
+
+		checkpoint.LastID = id
+		checkpoint.Done = result.Done
+		checkpoint.Failed = result.Failed
+		if err := saveCheckpoint(opts.CheckpointPath, checkpoint); err != nil {
+			return result, err
+		}
+
+		processed := i + 1
+		left := len(result.Pending) - processed
+		eta := time.Duration(int64(time.Since(started)) / int64(processed) * int64(left))
+		fmt.Printf("[%d/%d] %s - %d remaining, ETA %s\n", processed, len(result.Pending), id, left, eta.Round(time.Second))
+	}
+
+	// The crawl is complete, so the next one starts from the beginning.
+	if opts.CheckpointPath != "" {
+		if err := os.Remove(opts.CheckpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// crawlOne maps or queues a single ID, turning a panic into an error so one bad
+// entry cannot stop the crawl.
+func crawlOne(ctx context.Context, repo database.Repository, id string, opts Options) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if opts.Queue != nil {
+		return opts.Queue(ctx, id)
+	}
+
+	_, _, err = mappings.LoadMappings(ctx, repo, struct {
+		ID      string
+		Type    types.Type
+		Formats []types.Format
+	}{
+		ID:      id,
+		Type:    opts.Type,
+		Formats: opts.Formats,
+	})
+	return err
+}
+
+// loadCheckpoint reads the checkpoint file. A missing file means a fresh crawl.
+func loadCheckpoint(path string) (Checkpoint, error) {
+	if path == "" {
+		return Checkpoint{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Checkpoint{}, nil
+		}
+		return Checkpoint{}, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+
+	return checkpoint, nil
+}
+
+// saveCheckpoint writes the checkpoint to a temporary file first so a crash never
+// leaves a half written one behind.
+func saveCheckpoint(path string, checkpoint Checkpoint) error {
+	if path == "" {
+		return nil
+	}
+
+	checkpoint.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
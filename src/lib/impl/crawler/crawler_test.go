@@ -0,0 +1,20 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCrawlOneRecoversPanics(t *testing.T) {
+	err := crawlOne(context.Background(), nil, "bad-id", Options{
+		Queue: func(ctx context.Context, id string) error {
+			var title *string
+			_ = *title
+			return nil
+		},
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "panic: ") {
+		t.Fatalf("crawlOne = %v, want the panic as an error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/database"
+	events "anify/eltik/go/src/lib"
+	"anify/eltik/go/src/types"
+	"context"
+)
+
+// RefreshChapters fetches the chapters of a stored manga again from every mapped
+// provider and saves them. A ChaptersUpdated event is published for each provider
+// whose latest chapter is past the one stored before. It returns nil when the manga
+// is not stored.
+func RefreshChapters(ctx context.Context, repo database.Repository, id string) (*types.Manga, error) {
+	manga, err := repo.GetMangaByID(ctx, id)
+	if err != nil || manga == nil {
+		return nil, err
+	}
+
+	previous := make(map[string]float64)
+	for _, data := range manga.Chapters.Data {
+		previous[data.ProviderID] = latestChapter(data.Chapters)
+	}
+
+	media := manga.ToMedia()
+	media.Chapters = types.ChapterCollection{}
+	media.CurrentChapter = nil
+	loadChapters(&media)
+
+	// Keep what is stored when every provider failed or returned nothing.
+	if len(media.Chapters.Data) == 0 {
+		return manga, nil
+	}
+
+	if err := repo.InsertMedia(ctx, media); err != nil {
+		return nil, err
+	}
+
+	for _, data := range media.Chapters.Data {
+		before := previous[data.ProviderID]
+		latest := data.Chapters[len(data.Chapters)-1]
+		if latest.Number <= before {
+			continue
+		}
+
+		count := 0
+		for _, chapter := range data.Chapters {
+			if chapter.Number > before {
+				count++
+			}
+		}
+
+		events.Publish(events.ChaptersUpdated{
+			MediaID:     media.ID,
+			ProviderID:  data.ProviderID,
+			Previous:    before,
+			Latest:      latest.Number,
+			LatestTitle: latest.Title,
+			New:         count,
+		})
+	}
+
+	updated := media.ToManga()
+	return &updated, nil
+}
+
+// latestChapter returns the highest chapter number, or 0 for no chapters.
+func latestChapter(chapters []types.Chapter) float64 {
+	latest := 0.0
+	for _, chapter := range chapters {
+		if chapter.Number > latest {
+			latest = chapter.Number
+		}
+	}
+	return latest
+}
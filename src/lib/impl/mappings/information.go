@@ -0,0 +1,429 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/database"
+	providers "anify/eltik/go/src/mappings"
+	"anify/eltik/go/src/types"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// informationKeys lists every MediaInfo field a provider can put in its shared or
+// priority area.
+var informationKeys = types.MediaInfoKeys{
+	"title", "artwork", "synonyms", "totalEpisodes", "currentEpisode", "bannerImage",
+	"coverImage", "color", "season", "year", "status", "genres", "description", "format",
+	"duration", "trailer", "countryOfOrigin", "tags", "relations", "characters",
+	"totalChapters", "totalVolumes", "author", "publisher",
+}
+
+// RefreshInformation runs the information providers again for a stored anime or
+// manga and saves the merged result. It returns nil when the ID is not stored.
+func RefreshInformation(ctx context.Context, repo database.Repository, id string) (*types.Media, error) {
+	var media types.Media
+
+	manga, err := repo.GetMangaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if manga != nil {
+		media = manga.ToMedia()
+	} else {
+		anime, err := repo.GetAnimeByID(ctx, id)
+		if err != nil || anime == nil {
+			return nil, err
+		}
+		media = anime.ToMedia()
+	}
+
+	loadInformation(&media)
+
+	if err := repo.InsertMedia(ctx, media); err != nil {
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// loadInformation runs every information provider for the media's type and merges
+// what they return into it. A provider that fails is logged and skipped.
+func loadInformation(media *types.Media) {
+	for _, provider := range *providers.GetInformationProviders() {
+		if string(provider.GetType()) != string(media.Type) {
+			continue
+		}
+
+		info, err := provider.Info(*media)
+		if err != nil {
+			log.Printf("Failed to load information from %s for %s: %v", provider.GetID(), media.ID, err)
+			continue
+		}
+
+		mergeInformation(media, provider.GetID(), time.Now(), info, provider.GetSharedArea(), provider.GetPriorityArea())
+	}
+
+	media.AverageRating = average(media.Rating)
+	media.AveragePopularity = average(media.Popularity)
+}
+
+// mergeInformation applies one provider's information to the media. Keys in the
+// shared area are unioned with the existing values, or only filled in when the field
+// is a single value. Keys in the priority area overwrite what is there, lists
+// included. Ratings and popularity are always recorded under the provider's ID.
+// Every value the provider supplied is added to the media's provenance along with
+// what it did to the field.
+func mergeInformation(media *types.Media, providerId string, fetchedAt time.Time, info types.MediaInfo, shared types.MediaInfoKeys, priority types.MediaInfoKeys) {
+	if media.Provenance == nil {
+		media.Provenance = types.Provenance{}
+	}
+
+	merge := func(key string, overwrite bool) {
+		value := infoValue(info, key)
+		if value == nil {
+			return
+		}
+
+		previous := media.Provenance.Current(key)
+		action := types.ProvenanceKept
+		if mergeKey(media, key, info, overwrite) {
+			switch {
+			case overwrite && previous != "":
+				action = types.ProvenanceOverwrote
+			case isListKey(key):
+				action = types.ProvenanceMerged
+			default:
+				action = types.ProvenanceSet
+			}
+		}
+
+		media.Provenance.Record(key, types.FieldSource{
+			ProviderID: providerId,
+			FetchedAt:  fetchedAt,
+			Action:     action,
+			Value:      value,
+		})
+	}
+
+	for _, key := range shared {
+		merge(key, false)
+	}
+	for _, key := range priority {
+		merge(key, true)
+	}
+
+	if info.Rating != nil {
+		if media.Rating == nil {
+			media.Rating = types.Rating{}
+		}
+		media.Rating[providerId] = *info.Rating
+	}
+	if info.Popularity != nil {
+		if media.Popularity == nil {
+			media.Popularity = types.Popularity{}
+		}
+		media.Popularity[providerId] = *info.Popularity
+	}
+}
+
+// mergeKey merges a single MediaInfo field into the media and reports whether it
+// changed. Empty provider values never replace anything.
+func mergeKey(media *types.Media, key string, info types.MediaInfo, overwrite bool) bool {
+	switch key {
+	case "title":
+		changed := mergeString(&media.Title.Romaji, info.Title.Romaji, overwrite)
+		changed = mergeString(&media.Title.English, info.Title.English, overwrite) || changed
+		changed = mergeString(&media.Title.Native, info.Title.Native, overwrite) || changed
+		return changed
+	case "artwork":
+		return mergeList(&media.Artwork, info.Artwork, func(a types.Artwork) string { return a.Img }, overwrite)
+	case "synonyms":
+		return mergeList(&media.Synonyms, info.Synonyms, foldKey, overwrite)
+	case "genres":
+		return mergeList(&media.Genres, info.Genres, foldKey, overwrite)
+	case "tags":
+		return mergeList(&media.Tags, info.Tags, foldKey, overwrite)
+	case "relations":
+		return mergeList(&media.Relations, info.Relations, func(r types.Relations) string { return string(r.Type) + "/" + r.ID }, overwrite)
+	case "characters":
+		return mergeList(&media.Characters, info.Characters, func(c types.Character) string { return foldKey(c.Name) }, overwrite)
+	case "totalEpisodes":
+		return mergeInt(&media.TotalEpisodes, info.TotalEpisodes, overwrite)
+	case "currentEpisode":
+		return mergeInt(&media.CurrentEpisode, info.CurrentEpisode, overwrite)
+	case "totalChapters":
+		return mergeInt(&media.TotalChapters, info.TotalChapters, overwrite)
+	case "totalVolumes":
+		return mergeInt(&media.TotalVolumes, info.TotalVolumes, overwrite)
+	case "year":
+		return mergeInt(&media.Year, info.Year, overwrite)
+	case "duration":
+		return mergeInt(&media.Duration, info.Duration, overwrite)
+	case "bannerImage":
+		return mergeString(&media.BannerImage, info.BannerImage, overwrite)
+	case "coverImage":
+		return mergeString(&media.CoverImage, info.CoverImage, overwrite)
+	case "color":
+		return mergeString(&media.Color, info.Color, overwrite)
+	case "description":
+		return mergeString(&media.Description, info.Description, overwrite)
+	case "trailer":
+		return mergeString(&media.Trailer, info.Trailer, overwrite)
+	case "countryOfOrigin":
+		return mergeString(&media.CountryOfOrigin, info.CountryOfOrigin, overwrite)
+	case "author":
+		return mergeString(&media.Author, info.Author, overwrite)
+	case "publisher":
+		return mergeString(&media.Publisher, info.Publisher, overwrite)
+	case "status":
+		if info.Status == nil || *info.Status == "" || *info.Status == string(types.StatusUnknown) {
+			return false
+		}
+		if media.Status != nil && *media.Status != types.StatusUnknown && (!overwrite || string(*media.Status) == *info.Status) {
+			return false
+		}
+		status := types.Status(*info.Status)
+		media.Status = &status
+		return true
+	case "season":
+		if info.Season == "" || info.Season == types.SeasonUnknown {
+			return false
+		}
+		if media.Season != "" && media.Season != types.SeasonUnknown && (!overwrite || media.Season == info.Season) {
+			return false
+		}
+		media.Season = info.Season
+		return true
+	case "format":
+		if info.Format == "" || info.Format == types.FormatUnknown {
+			return false
+		}
+		if media.Format != "" && media.Format != types.FormatUnknown && (!overwrite || media.Format == info.Format) {
+			return false
+		}
+		media.Format = info.Format
+		return true
+	}
+
+	log.Printf("Unknown information key: %s", key)
+	return false
+}
+
+// infoValue returns the provider's value for a MediaInfo field, or nil when it is
+// empty.
+func infoValue(info types.MediaInfo, key string) interface{} {
+	switch key {
+	case "title":
+		if info.Title.Romaji == nil && info.Title.English == nil && info.Title.Native == nil {
+			return nil
+		}
+		return info.Title
+	case "artwork":
+		return listValue(info.Artwork)
+	case "synonyms":
+		return listValue(info.Synonyms)
+	case "genres":
+		return listValue(info.Genres)
+	case "tags":
+		return listValue(info.Tags)
+	case "relations":
+		return listValue(info.Relations)
+	case "characters":
+		return listValue(info.Characters)
+	case "totalEpisodes":
+		return intValue(info.TotalEpisodes)
+	case "currentEpisode":
+		return intValue(info.CurrentEpisode)
+	case "totalChapters":
+		return intValue(info.TotalChapters)
+	case "totalVolumes":
+		return intValue(info.TotalVolumes)
+	case "year":
+		return intValue(info.Year)
+	case "duration":
+		return intValue(info.Duration)
+	case "bannerImage":
+		return stringValue(info.BannerImage)
+	case "coverImage":
+		return stringValue(info.CoverImage)
+	case "color":
+		return stringValue(info.Color)
+	case "description":
+		return stringValue(info.Description)
+	case "trailer":
+		return stringValue(info.Trailer)
+	case "countryOfOrigin":
+		return stringValue(info.CountryOfOrigin)
+	case "author":
+		return stringValue(info.Author)
+	case "publisher":
+		return stringValue(info.Publisher)
+	case "status":
+		if info.Status == nil || *info.Status == "" || *info.Status == string(types.StatusUnknown) {
+			return nil
+		}
+		return *info.Status
+	case "season":
+		if info.Season == "" || info.Season == types.SeasonUnknown {
+			return nil
+		}
+		return info.Season
+	case "format":
+		if info.Format == "" || info.Format == types.FormatUnknown {
+			return nil
+		}
+		return info.Format
+	}
+
+	return nil
+}
+
+// isListKey reports whether the field is a list that shared providers are unioned
+// into.
+func isListKey(key string) bool {
+	switch key {
+	case "artwork", "synonyms", "genres", "tags", "relations", "characters":
+		return true
+	}
+	return false
+}
+
+func listValue[T any](values []T) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+func stringValue(value *string) interface{} {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return *value
+}
+
+func intValue(value *int) interface{} {
+	if value == nil || *value == 0 {
+		return nil
+	}
+	return *value
+}
+
+// mergeString sets dst to value when value is not empty and either dst is empty or
+// overwrite is set.
+func mergeString(dst **string, value *string, overwrite bool) bool {
+	if value == nil || *value == "" {
+		return false
+	}
+	if *dst != nil && **dst != "" && (!overwrite || **dst == *value) {
+		return false
+	}
+
+	v := *value
+	*dst = &v
+	return true
+}
+
+// mergeInt is mergeString for numbers, treating 0 as empty.
+func mergeInt(dst **int, value *int, overwrite bool) bool {
+	if value == nil || *value == 0 {
+		return false
+	}
+	if *dst != nil && **dst != 0 && (!overwrite || **dst == *value) {
+		return false
+	}
+
+	v := *value
+	*dst = &v
+	return true
+}
+
+// recordBase adds every non-empty field of the base provider's data to the media's
+// provenance, as the entry is created from it.
+func recordBase(media *types.Media, providerId string, fetchedAt time.Time, baseData types.MediaInfo) {
+	if media.Provenance == nil {
+		media.Provenance = types.Provenance{}
+	}
+
+	for _, key := range informationKeys {
+		value := infoValue(baseData, key)
+		if value == nil {
+			continue
+		}
+
+		media.Provenance.Record(key, types.FieldSource{
+			ProviderID: providerId,
+			FetchedAt:  fetchedAt,
+			Action:     types.ProvenanceSet,
+			Value:      value,
+		})
+	}
+}
+
+// mergeList unions values into dst, or replaces dst with them when overwrite is set.
+// Empty provider values never replace anything.
+func mergeList[T any](dst *[]T, values []T, key func(T) string, overwrite bool) bool {
+	if !overwrite || len(values) == 0 {
+		return union(dst, values, key)
+	}
+
+	var replaced []T
+	if !union(&replaced, values, key) {
+		return false
+	}
+	if len(replaced) == len(*dst) {
+		same := true
+		for i := range replaced {
+			if key(replaced[i]) != key((*dst)[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+
+	*dst = replaced
+	return true
+}
+
+// union appends the values whose key is not in dst yet, keeping the existing order.
+func union[T any](dst *[]T, values []T, key func(T) string) bool {
+	seen := make(map[string]bool, len(*dst))
+	for _, existing := range *dst {
+		seen[key(existing)] = true
+	}
+
+	changed := false
+	for _, value := range values {
+		k := key(value)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		*dst = append(*dst, value)
+		changed = true
+	}
+	return changed
+}
+
+func foldKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// average returns the mean of the values, or nil when there are none.
+func average(values map[string]float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+
+	avg := total / float64(len(values))
+	return &avg
+}
@@ -0,0 +1,126 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMergeInformation(t *testing.T) {
+	fetchedAt := time.Date(2024, 10, 25, 0, 0, 0, 0, time.UTC)
+
+	media := types.Media{
+		Title:       types.Title{Romaji: ptr("Wan Piisu")},
+		Description: ptr("Base description"),
+		Genres:      []string{"Action", "Adventure"},
+		Synonyms:    []string{"OP"},
+	}
+	recordBase(&media, "base", fetchedAt, types.MediaInfo{
+		Title:       media.Title,
+		Description: media.Description,
+		Genres:      media.Genres,
+		Synonyms:    media.Synonyms,
+	})
+
+	shared := types.MediaInfoKeys{"description", "synonyms", "year"}
+	priority := types.MediaInfoKeys{"genres", "coverImage"}
+
+	mergeInformation(&media, "shared", fetchedAt, types.MediaInfo{
+		Description: ptr("Shared description"),
+		Synonyms:    []string{"op", "One Piece"},
+		Year:        ptr(1997),
+		Genres:      []string{"Comedy"},
+		Rating:      ptr(8.0),
+		Popularity:  ptr(100.0),
+	}, shared, types.MediaInfoKeys{})
+
+	mergeInformation(&media, "priority", fetchedAt, types.MediaInfo{
+		Description: ptr("Priority description"),
+		Genres:      []string{"Drama", "drama", "Fantasy"},
+		CoverImage:  ptr("https://example.com/cover.jpg"),
+		Rating:      ptr(9.0),
+		Popularity:  ptr(300.0),
+	}, types.MediaInfoKeys{}, priority)
+
+	// The shared provider fills in empty fields and unions lists but keeps the rest.
+	if *media.Description != "Base description" {
+		t.Errorf("description = %q, want the base description kept", *media.Description)
+	}
+	if want := []string{"OP", "One Piece"}; !reflect.DeepEqual(media.Synonyms, want) {
+		t.Errorf("synonyms = %v, want %v", media.Synonyms, want)
+	}
+	if media.Year == nil || *media.Year != 1997 {
+		t.Errorf("year = %v, want 1997", media.Year)
+	}
+
+	// The priority provider replaces lists too, without duplicates.
+	if want := []string{"Drama", "Fantasy"}; !reflect.DeepEqual(media.Genres, want) {
+		t.Errorf("genres = %v, want %v", media.Genres, want)
+	}
+	if media.CoverImage == nil || *media.CoverImage != "https://example.com/cover.jpg" {
+		t.Errorf("coverImage = %v", media.CoverImage)
+	}
+
+	actions := map[string]string{
+		"description": types.ProvenanceKept,
+		"synonyms":    types.ProvenanceMerged,
+		"year":        types.ProvenanceSet,
+		"genres":      types.ProvenanceOverwrote,
+		"coverImage":  types.ProvenanceSet,
+	}
+	for key, want := range actions {
+		sources := media.Provenance[key]
+		if len(sources) == 0 {
+			t.Errorf("%s has no provenance", key)
+			continue
+		}
+		if got := sources[len(sources)-1].Action; got != want {
+			t.Errorf("%s action = %q, want %q", key, got, want)
+		}
+	}
+	if got := media.Provenance.Current("genres"); got != "priority" {
+		t.Errorf("genres come from %q, want priority", got)
+	}
+	if got := media.Provenance.Current("description"); got != "base" {
+		t.Errorf("description comes from %q, want base", got)
+	}
+
+	wantRating := types.Rating{"shared": 8, "priority": 9}
+	if !reflect.DeepEqual(media.Rating, wantRating) {
+		t.Errorf("rating = %v, want %v", media.Rating, wantRating)
+	}
+	if avg := average(media.Rating); avg == nil || *avg != 8.5 {
+		t.Errorf("average rating = %v, want 8.5", avg)
+	}
+	if avg := average(media.Popularity); avg == nil || *avg != 200 {
+		t.Errorf("average popularity = %v, want 200", avg)
+	}
+}
+
+func TestMergeInformationSameList(t *testing.T) {
+	media := types.Media{Genres: []string{"Action", "Drama"}}
+
+	// Overwriting a list with the same values is not a change.
+	mergeInformation(&media, "priority", time.Now(), types.MediaInfo{
+		Genres: []string{"Action", "Drama"},
+	}, types.MediaInfoKeys{}, types.MediaInfoKeys{"genres"})
+
+	sources := media.Provenance["genres"]
+	if len(sources) != 1 || sources[0].Action != types.ProvenanceKept {
+		t.Errorf("genres provenance = %+v, want one kept source", sources)
+	}
+}
+
+func TestAverage(t *testing.T) {
+	if avg := average(nil); avg != nil {
+		t.Errorf("average(nil) = %v, want nil", *avg)
+	}
+	if avg := average(map[string]float64{"a": 7}); avg == nil || *avg != 7 {
+		t.Errorf("average of one value = %v, want 7", avg)
+	}
+}
@@ -1,63 +1,143 @@
 package mappings
 
 import (
-	database_fetch "anify/eltik/go/src/database/impl/fetch"
+	"anify/eltik/go/src/database"
 	events "anify/eltik/go/src/lib"
 	"anify/eltik/go/src/lib/impl/helper"
 	providers "anify/eltik/go/src/mappings"
 	"anify/eltik/go/src/types"
+	"context"
 	"fmt"
 	"log"
-	"strings"
+	"time"
 )
 
-func LoadMappings(data struct {
+// LoadMappings finds the provider mappings for an ID and stores the resulting media
+// through the repository. A MappingLoadCompleted or MappingLoadFailed event is
+// published once it is done. Unless the entry was already stored, a MatchReport
+// explaining every candidate is saved as well.
+func LoadMappings(ctx context.Context, repo database.Repository, data struct {
 	ID      string
 	Type    types.Type
 	Formats []types.Format
 }) ([]types.Anime, []types.Manga, error) {
-	existing, err := database_fetch.Get(data.ID, data.Type)
+	start := time.Now()
+
+	result, err := loadMappings(ctx, repo, data)
+	if result.report != nil {
+		saveReport(ctx, repo, result.report, start, err)
+	}
+	if err != nil {
+		events.Publish(events.MappingLoadFailed{
+			MediaID:    data.ID,
+			Type:       string(data.Type),
+			DurationMs: time.Since(start).Milliseconds(),
+			Error:      err.Error(),
+		})
+		return result.anime, result.manga, err
+	}
+
+	var found []events.ProviderMapping
+	for _, anime := range result.anime {
+		found = append(found, providerMappings(anime.Mappings)...)
+	}
+	for _, manga := range result.manga {
+		found = append(found, providerMappings(manga.Mappings)...)
+	}
+
+	events.Publish(events.MappingLoadCompleted{
+		MediaID:    data.ID,
+		Type:       string(data.Type),
+		Mappings:   found,
+		Existing:   result.existing,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+
+	return result.anime, result.manga, nil
+}
+
+// saveReport stores the match report of a run. A failure to store it is only
+// logged, as the mappings themselves are already saved.
+func saveReport(ctx context.Context, repo database.Repository, report *types.MatchReport, start time.Time, err error) {
+	if err != nil {
+		report.Outcome = types.MatchOutcomeFailed
+		report.Error = err.Error()
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+	report.CreatedAt = time.Now()
+
+	if err := repo.SaveMatchReport(ctx, *report); err != nil {
+		log.Printf("Failed to save match report for %s: %v", report.MediaID, err)
+	}
+}
+
+// mappingLoad is what loadMappings found or stored.
+type mappingLoad struct {
+	anime []types.Anime
+	manga []types.Manga
+	// existing is true when the entry was already stored.
+	existing bool
+	// report explains the mapping decisions. It is nil for stored entries.
+	report *types.MatchReport
+}
+
+func loadMappings(ctx context.Context, repo database.Repository, data struct {
+	ID      string
+	Type    types.Type
+	Formats []types.Format
+}) (mappingLoad, error) {
+	existing, err := repo.Get(ctx, data.ID, data.Type)
 	if err != nil {
 		log.Println("Failed to fetch existing data:", err)
-		return nil, nil, err
+		return mappingLoad{}, err
 	}
 
-	if existing != nil {
-		if existingData, ok := existing.(types.Anime); ok && existingData.ID != "" {
-			events.Bus.Publish(events.COMPLETED_MAPPING_LOAD)
-			return nil, nil, nil
-		}
+	switch existingData := existing.(type) {
+	case *types.Anime:
+		return mappingLoad{anime: []types.Anime{*existingData}, existing: true}, nil
+	case *types.Manga:
+		return mappingLoad{manga: []types.Manga{*existingData}, existing: true}, nil
 	}
 
 	log.Println("No existing data found, fetching mappings.")
 
+	report := &types.MatchReport{
+		MediaID:  data.ID,
+		Type:     data.Type,
+		Formats:  data.Formats,
+		Searches: []types.SearchReport{},
+	}
+
 	baseProviders := providers.GetBaseProviders()
 
 	var baseData *types.MediaInfo
+	var baseProviderId string
+	var fetchedAt time.Time
 
+	// A job can carry several formats once queued requests are merged, so the
+	// first base provider supporting any of them is used.
 	for _, provider := range *baseProviders {
-		for _, format := range provider.GetFormats() {
-			println("Checking format:", format)
-			if format == data.Formats[0] {
-				media, err := provider.GetMedia(data.ID)
-				if err != nil {
-					fmt.Println("Error fetching media:", err)
-					return nil, nil, err
-				}
-				baseData = &media
-				break
-			}
+		if !supportsFormat(provider.GetFormats(), data.Formats) {
+			continue
 		}
-		if baseData != nil {
-			break
+
+		report.BaseProvider = provider.GetID()
+		media, err := provider.GetMedia(data.ID)
+		if err != nil {
+			fmt.Println("Error fetching media:", err)
+			return mappingLoad{report: report}, err
 		}
+		baseData = &media
+		baseProviderId = provider.GetID()
+		fetchedAt = time.Now()
+		break
 	}
 
 	if baseData == nil || ((baseData.Title.English == nil || len(*baseData.Title.English) == 0) && (baseData.Title.Romaji == nil || len(*baseData.Title.Romaji) == 0) && (baseData.Title.Native == nil || len(*baseData.Title.Native) == 0)) {
 		println("Media not found. Skipping...")
 
-		events.Bus.Publish(events.COMPLETED_MAPPING_LOAD)
-		return nil, nil, nil
+		report.Outcome = types.MatchOutcomeNotFound
+		return mappingLoad{report: report}, nil
 	}
 
 	var suitableProviders types.MappingsProviders
@@ -65,151 +145,169 @@ func LoadMappings(data struct {
 	if data.Type == types.TypeAnime {
 		animeProviders := providers.GetAnimeProviders()
 		for _, provider := range *animeProviders {
-			for _, format := range provider.GetFormats() {
-				if format == data.Formats[0] {
-					suitableProviders.AnimeProviders = append(suitableProviders.AnimeProviders, provider)
-					break
-				}
+			if supportsFormat(provider.GetFormats(), data.Formats) {
+				suitableProviders.AnimeProviders = append(suitableProviders.AnimeProviders, provider)
 			}
 		}
 	} else {
 		mangaProviders := providers.GetMangaProviders()
 		for _, provider := range *mangaProviders {
-			for _, format := range provider.GetFormats() {
-				if format == data.Formats[0] {
-					suitableProviders.MangaProviders = append(suitableProviders.MangaProviders, provider)
-					break
-				}
+			if supportsFormat(provider.GetFormats(), data.Formats) {
+				suitableProviders.MangaProviders = append(suitableProviders.MangaProviders, provider)
 			}
 		}
 	}
 
-	println("Searching for media...")
-	results := searchMedia(*baseData, suitableProviders)
-	println("Found", len(results), "results.")
-
-	var mappings []types.MappedResult = make([]types.MappedResult, 0)
+	// Pinned providers and providers marked as having no match are not searched.
+	overrides, err := repo.ListMappingOverrides(ctx, data.ID, data.Type)
+	if err != nil {
+		log.Println("Failed to fetch mapping overrides:", err)
+		return mappingLoad{report: report}, err
+	}
+	plan := newOverridePlan(overrides)
+	suitableProviders = plan.filterProviders(suitableProviders)
 
-	for _, result := range results {
-		var title string
-		if baseData.Title.English != nil && len(*baseData.Title.English) > 0 {
-			title = *baseData.Title.English
-		} else if baseData.Title.Romaji != nil && len(*baseData.Title.Romaji) > 0 {
-			title = *baseData.Title.Romaji
-		} else if baseData.Title.Native != nil && len(*baseData.Title.Native) > 0 {
-			title = *baseData.Title.Native
-		}
+	println("Searching for media...")
+	searches := searchMedia(*baseData, suitableProviders)
+	println("Found", len(searches), "results.")
+
+	var title string
+	if baseData.Title.English != nil && len(*baseData.Title.English) > 0 {
+		title = *baseData.Title.English
+	} else if baseData.Title.Romaji != nil && len(*baseData.Title.Romaji) > 0 {
+		title = *baseData.Title.Romaji
+	} else if baseData.Title.Native != nil && len(*baseData.Title.Native) > 0 {
+		title = *baseData.Title.Native
+	}
 
-		providerTitles := make([][]string, 0)
-		for _, r := range result {
-			titles := append([]string{r.Title}, r.AltTitles...)
-			filteredTitles := []string{}
-			for _, title := range titles {
-				if helper.IsString(title) {
-					filteredTitles = append(filteredTitles, title)
-				}
-			}
+	matchTitles := baseMatchTitles(*baseData)
+	for _, t := range matchTitles {
+		report.Titles = append(report.Titles, t.Title)
+	}
 
-			providerTitles = append(providerTitles, filteredTitles)
-		}
+	var mappings []types.MappedResult = make([]types.MappedResult, 0)
 
-		if len(providerTitles) == 0 {
-			println("No titles found for " + title + " in provider " + result[0].ProviderId)
+	report.Searches = append(report.Searches, plan.reports()...)
+	for _, override := range plan.order {
+		if override.Kind != database.OverridePin {
 			continue
 		}
+		mappings = append(mappings, types.MappedResult{
+			ID:   data.ID,
+			Slug: Slugify(title),
+			Data: types.Result{
+				ID:         override.ProviderMediaID,
+				ProviderId: override.ProviderID,
+			},
+			Similarity: 1,
+		})
+	}
 
-		println("Found titles for provider " + result[0].ProviderId)
+	for _, search := range searches {
+		mapped, searchReport := matchSearch(search, *baseData, title, matchTitles, mappings, plan)
+		report.Searches = append(report.Searches, searchReport)
 
-		titles := []string{
-			*baseData.Title.English,
-			*baseData.Title.Romaji,
-			*baseData.Title.Native,
+		if mapped != nil {
+			mapped.ID = data.ID
+			mapped.Slug = Slugify(title)
+			mappings = append(mappings, *mapped)
 		}
+	}
 
-		titles = append(titles, baseData.Synonyms...)
+	report.Mapped = len(mappings)
 
-		var filteredTitles []string
-		for _, title := range titles {
-			if helper.IsString(title) {
-				filteredTitles = append(filteredTitles, clean(title))
-			}
-		}
+	if len(mappings) == 0 {
+		println("No mappings found.")
+		report.Outcome = types.MatchOutcomeNoMappings
+		return mappingLoad{report: report}, nil
+	}
 
-		bestMatchIndex := FindBestMatch2DArray(filteredTitles, providerTitles)
+	println("Found", len(mappings), "mappings.")
 
-		if bestMatchIndex.BestMatch.Rating < 0.7 {
-			//console.log(colors.gray("Unable to match ") + colors.blue(title) + colors.gray(" for ") + colors.blue(suitableProviders[i].id) + colors.gray(".") + colors.gray(" Best match rating: ") + colors.blue(bestMatchIndex.bestMatch.rating + "") + colors.gray(". ID: ") + colors.blue(providerData[bestMatchIndex.bestMatchIndex].id) + colors.gray(". Title: ") + colors.blue(providerData[bestMatchIndex.bestMatchIndex].title) + colors.gray("."));
-			continue
-		}
+	var animeResults []types.Anime
+	var mangaResults []types.Manga
 
-		best := result[bestMatchIndex.BestMatchIndex]
+	for _, media := range createMedia(mappings, *baseData, baseProviderId, fetchedAt, data.Type) {
+		loadInformation(&media)
 
-		if best.Format != types.FormatUnknown && baseData.Format != types.FormatUnknown && best.Format != baseData.Format {
-			continue
+		if media.Type == types.TypeManga {
+			loadChapters(&media)
 		}
 
-		if best.Year != 0 && *baseData.Year != 0 && best.Year != *baseData.Year {
-			continue
+		if err := repo.InsertMedia(ctx, media); err != nil {
+			log.Println("Failed to save media:", err)
+			return mappingLoad{anime: animeResults, manga: mangaResults, report: report}, err
 		}
 
-		var altTitles []string
+		events.Publish(events.EntryCreated{
+			MediaID:  media.ID,
+			Type:     string(media.Type),
+			Slug:     media.Slug,
+			Mappings: providerMappings(media.Mappings),
+		})
 
-		// Append non-nil Title fields to altTitles
-		if baseData.Title.Romaji != nil {
-			altTitles = append(altTitles, *baseData.Title.Romaji)
+		if media.Type == types.TypeAnime {
+			animeResults = append(animeResults, media.ToAnime())
+		} else {
+			mangaResults = append(mangaResults, media.ToManga())
 		}
-		if baseData.Title.English != nil {
-			altTitles = append(altTitles, *baseData.Title.English)
-		}
-		if baseData.Title.Native != nil {
-			altTitles = append(altTitles, *baseData.Title.Native)
-		}
-
-		// Append synonyms
-		altTitles = append(altTitles, baseData.Synonyms...)
+	}
 
-		sim := Similarity(title, best.Title, altTitles)
+	report.Outcome = types.MatchOutcomeMapped
+	return mappingLoad{anime: animeResults, manga: mangaResults, report: report}, nil
+}
 
-		if sim.Value < 0.4 {
-			continue
+// baseMatchTitles lists the titles and synonyms of the base entry that results are
+// matched against. Only the romaji title gets the romaji spelling rules: synonyms
+// are not labelled with a language and are often English, which those rules would
+// mangle.
+func baseMatchTitles(baseData types.MediaInfo) []MatchTitle {
+	var matchTitles []MatchTitle
+	for i, t := range []*string{baseData.Title.English, baseData.Title.Romaji, baseData.Title.Native} {
+		if t != nil && helper.IsString(*t) {
+			matchTitles = append(matchTitles, MatchTitle{Title: *t, Romaji: i == 1})
 		}
-
-		// if (mappings.filter((m) => m.data.id === best.id).length > 0) continue;
-		if len(mappings) > 0 {
-			for _, m := range mappings {
-				if m.Data.ID == best.ID {
-					continue
-				}
-			}
+	}
+	for _, synonym := range baseData.Synonyms {
+		if helper.IsString(synonym) {
+			matchTitles = append(matchTitles, MatchTitle{Title: synonym})
 		}
-
-		mappings = append(mappings, types.MappedResult{
-			ID:         best.ID,
-			Slug:       Slugify(best.Title),
-			Data:       best,
-			Similarity: sim.Value,
-		})
 	}
+	return matchTitles
+}
 
-	if len(mappings) == 0 {
-		println("No mappings found.")
+// supportsFormat reports whether a provider with the given formats handles any of
+// the requested ones.
+func supportsFormat(formats []types.Format, requested []types.Format) bool {
+	for _, format := range formats {
+		for _, wanted := range requested {
+			if format == wanted {
+				return true
+			}
+		}
 	}
-
-	println("Found", len(mappings), "mappings.")
-
-	return nil, nil, nil
+	return false
 }
 
-func searchMedia(baseData types.MediaInfo, suitableProviders types.MappingsProviders) [][]types.Result {
-	titlesToSearch := []string{
-		*baseData.Title.English,
-		*baseData.Title.Romaji,
-		*baseData.Title.Native,
+// searchMedia searches every provider for each of the base titles and synonyms. The
+// base provider leaves out titles it has no language for, and the year when it is
+// unknown, in which case 0 is passed.
+func searchMedia(baseData types.MediaInfo, suitableProviders types.MappingsProviders) []providerSearch {
+	var titlesToSearch []string
+	for _, title := range []*string{baseData.Title.English, baseData.Title.Romaji, baseData.Title.Native} {
+		if title != nil {
+			titlesToSearch = append(titlesToSearch, *title)
+		}
 	}
 
 	titlesToSearch = append(titlesToSearch, baseData.Synonyms...)
 
-	var allResults [][]types.Result
+	year := 0
+	if baseData.Year != nil {
+		year = *baseData.Year
+	}
+
+	var allResults []providerSearch
 
 	for _, title := range titlesToSearch {
 		if title == "" {
@@ -217,127 +315,285 @@ func searchMedia(baseData types.MediaInfo, suitableProviders types.MappingsProvi
 		}
 
 		for _, provider := range suitableProviders.AnimeProviders {
-			results, err := provider.Search(title, baseData.Format, *baseData.Year)
+			results, err := provider.Search(title, baseData.Format, year)
 			if err != nil {
 				log.Println("Error searching for anime:", err)
-				continue
 			}
-			allResults = append(allResults, results)
+			allResults = append(allResults, providerSearch{providerId: provider.GetID(), query: title, results: results, err: err})
 		}
 
 		for _, provider := range suitableProviders.MangaProviders {
-			results, err := provider.Search(title, baseData.Format, *baseData.Year)
+			results, err := provider.Search(title, baseData.Format, year)
 			if err != nil {
 				log.Println("Error searching for manga:", err)
-				continue
 			}
-			allResults = append(allResults, results)
+			allResults = append(allResults, providerSearch{providerId: provider.GetID(), query: title, results: results, err: err})
 		}
 	}
 
 	return allResults
 }
 
-func createMedia(mappings []types.MappedResult, type_ types.Type) {
-	results := make([]types.Media, 0)
-	for _, mapping := range mappings {
-		hasPushed := false
+// providerSearch is what one provider returned when searched for one title.
+type providerSearch struct {
+	providerId string
+	query      string
+	results    []types.Result
+	err        error
+}
 
-		/*
-			animeProviders := providers.GetAnimeProviders()
-			mangaProviders := providers.GetMangaProviders()
+// matchSearch picks the search result that matches the base titles, if any, and
+// reports the rule that decided every candidate. The returned mapping has no media
+// ID or slug set yet. Only the best rated candidate goes through the format, year,
+// similarity, blocklist and duplicate checks; the others are marked MatchNotBest.
+func matchSearch(search providerSearch, baseData types.MediaInfo, title string, baseTitles []MatchTitle, mappings []types.MappedResult, plan overridePlan) (*types.MappedResult, types.SearchReport) {
+	config := GetMatchConfig(search.providerId)
+	report := types.SearchReport{
+		ProviderID:    search.providerId,
+		Query:         search.query,
+		Matcher:       config.Matcher.Name(),
+		MinRating:     config.MinRating,
+		MinSimilarity: config.MinSimilarity,
+		Candidates:    []types.CandidateReport{},
+	}
 
-			var providerType types.ProviderType
+	if search.err != nil {
+		report.Error = search.err.Error()
+		return nil, report
+	}
 
-			if type_ == types.TypeAnime {
-				for _, provider := range *animeProviders {
-					if provider.GetID() == mapping.Data.ProviderId {
-						providerType = provider.GetType()
-						break
-					}
-				}
-			} else {
-				for _, provider := range *mangaProviders {
-					if provider.GetID() == mapping.Data.ProviderId {
-						providerType = provider.GetType()
-						break
-					}
-				}
+	providerTitles := make([][]string, 0)
+	for _, r := range search.results {
+		titles := append([]string{r.Title}, r.AltTitles...)
+		filteredTitles := []string{}
+		for _, title := range titles {
+			if helper.IsString(title) {
+				filteredTitles = append(filteredTitles, title)
 			}
-		*/
+		}
 
-		for _, result := range results {
-			if result.ID == mapping.ID {
-				hasPushed = true
+		providerTitles = append(providerTitles, filteredTitles)
+	}
 
-				toPush := types.Mapping{
-					ID:         mapping.Data.ID,
-					ProviderID: mapping.Data.ProviderId,
-					Similarity: mapping.Similarity,
-				}
+	if len(providerTitles) == 0 {
+		println("No titles found for " + title + " in provider " + search.providerId)
+		return nil, report
+	}
+
+	println("Found titles for provider " + search.providerId)
+
+	bestMatchIndex := FindBestMatch2DArrayWith(config.Matcher, baseTitles, providerTitles)
+
+	for i, r := range search.results {
+		report.Candidates = append(report.Candidates, types.CandidateReport{
+			ID:           r.ID,
+			Title:        r.Title,
+			Format:       r.Format,
+			Year:         r.Year,
+			Rating:       bestMatchIndex.Candidates[i].Rating,
+			MatchedTitle: bestMatchIndex.Candidates[i].Target,
+			Rule:         types.MatchNotBest,
+		})
+	}
+	candidate := &report.Candidates[bestMatchIndex.BestMatchIndex]
+
+	if bestMatchIndex.BestMatch.Rating < config.MinRating {
+		println(fmt.Sprintf("Unable to match %s for %s. Best match rating: %.3f. ID: %s. Title: %s.", title, search.providerId, bestMatchIndex.BestMatch.Rating, candidate.ID, candidate.Title))
+		candidate.Rule = types.MatchRatingThreshold
+		return nil, report
+	}
+
+	best := search.results[bestMatchIndex.BestMatchIndex]
+
+	if best.Format != types.FormatUnknown && baseData.Format != types.FormatUnknown && best.Format != baseData.Format {
+		candidate.Rule = types.MatchFormatMismatch
+		return nil, report
+	}
+
+	if best.Year != 0 && baseData.Year != nil && *baseData.Year != 0 && best.Year != *baseData.Year {
+		candidate.Rule = types.MatchYearMismatch
+		return nil, report
+	}
+
+	sim := SimilarityWith(config.Matcher, best.Title, baseTitles)
+	candidate.Similarity = &sim.Value
+
+	if sim.Value < config.MinSimilarity {
+		candidate.Rule = types.MatchSimilarityThreshold
+		return nil, report
+	}
+
+	if plan.isBlocked(best.ProviderId, best.ID) {
+		candidate.Rule = types.MatchBlocked
+		return nil, report
+	}
+
+	for _, m := range mappings {
+		if m.Data.ID == best.ID && m.Data.ProviderId == best.ProviderId {
+			candidate.Rule = types.MatchDuplicate
+			return nil, report
+		}
+	}
 
-				result.Mappings = append(result.Mappings, toPush)
+	candidate.Rule = types.MatchAccepted
+	return &types.MappedResult{
+		Data:       best,
+		Similarity: sim.Value,
+	}, report
+}
+
+// createMedia groups the mapped results by media ID and builds the entries to
+// store, using the base provider's data for the title, synonyms and metadata.
+func createMedia(mappings []types.MappedResult, baseData types.MediaInfo, baseProviderId string, fetchedAt time.Time, type_ types.Type) []types.Media {
+	results := make([]types.Media, 0)
+	for _, mapping := range mappings {
+		hasPushed := false
+
+		toPush := types.Mapping{
+			ID:           mapping.Data.ID,
+			ProviderID:   mapping.Data.ProviderId,
+			Similarity:   mapping.Similarity,
+			ProviderType: getProviderType(mapping.Data.ProviderId, type_),
+		}
+
+		for i := range results {
+			if results[i].ID == mapping.ID {
+				hasPushed = true
+				results[i].Mappings = append(results[i].Mappings, toPush)
 			}
 		}
 
 		if !hasPushed {
+			var status *types.Status
+			if baseData.Status != nil {
+				s := types.Status(*baseData.Status)
+				status = &s
+			}
+
+			season := baseData.Season
+			if season == "" {
+				season = types.SeasonUnknown
+			}
+
+			format := baseData.Format
+			if format == "" {
+				format = types.FormatUnknown
+			}
+
 			data := types.Media{
-				ID:   mapping.ID,
-				Slug: mapping.Slug,
-				Type: type_,
-				Title: types.Title{
-					English: nil,
-					Romaji:  nil,
-					Native:  nil,
-				},
-				Mappings: []types.Mapping{
-					{
-						ID:         mapping.Data.ID,
-						ProviderID: mapping.Data.ProviderId,
-						Similarity: mapping.Similarity,
-					},
-				},
-				Synonyms:          []string{},
-				CountryOfOrigin:   nil,
-				CoverImage:        nil,
-				BannerImage:       nil,
-				Trailer:           nil,
-				Status:            nil,
-				Season:            types.SeasonUnknown,
-				CurrentEpisode:    nil,
-				Description:       nil,
-				Duration:          nil,
-				Color:             nil,
-				Year:              nil,
+				ID:                mapping.ID,
+				Slug:              mapping.Slug,
+				Type:              type_,
+				Title:             baseData.Title,
+				Mappings:          []types.Mapping{toPush},
+				Synonyms:          baseData.Synonyms,
+				CountryOfOrigin:   baseData.CountryOfOrigin,
+				CoverImage:        baseData.CoverImage,
+				BannerImage:       baseData.BannerImage,
+				Trailer:           baseData.Trailer,
+				Status:            status,
+				Season:            season,
+				CurrentEpisode:    baseData.CurrentEpisode,
+				Description:       baseData.Description,
+				Duration:          baseData.Duration,
+				Color:             baseData.Color,
+				Year:              baseData.Year,
 				Rating:            nil,
 				Popularity:        nil,
 				AverageRating:     nil,
 				AveragePopularity: nil,
-				Genres:            []string{},
-				Format:            types.FormatUnknown,
-				Relations:         []types.Relations{},
-				TotalEpisodes:     nil,
+				Genres:            baseData.Genres,
+				Format:            format,
+				Relations:         baseData.Relations,
+				TotalEpisodes:     baseData.TotalEpisodes,
 				Episodes:          types.EpisodeCollection{},
-				Tags:              []string{},
-				Artwork:           nil,
-				Characters:        nil,
+				Tags:              baseData.Tags,
+				Artwork:           baseData.Artwork,
+				Characters:        baseData.Characters,
 				CurrentChapter:    nil,
-				TotalVolumes:      nil,
-				Publisher:         nil,
-				Author:            nil,
-				TotalChapters:     nil,
+				TotalVolumes:      baseData.TotalVolumes,
+				Publisher:         baseData.Publisher,
+				Author:            baseData.Author,
+				TotalChapters:     baseData.TotalChapters,
 				Chapters:          types.ChapterCollection{},
 			}
+			recordBase(&data, baseProviderId, fetchedAt, baseData)
 
 			results = append(results, data)
 		}
 	}
 
-	for _, media := range results {
+	return results
+}
+
+// loadChapters fetches the chapters from every mapped manga provider and records
+// the most recent one.
+func loadChapters(media *types.Media) {
+	for _, mapping := range media.Mappings {
+		for _, provider := range *providers.GetMangaProviders() {
+			if provider.GetID() != mapping.ProviderID {
+				continue
+			}
 
+			chapters, err := provider.FetchChapters(mapping.ID)
+			if err != nil {
+				log.Println("Error fetching chapters:", err)
+				continue
+			}
+			if len(chapters) == 0 {
+				continue
+			}
+
+			media.Chapters.Data = append(media.Chapters.Data, types.ChapterData{
+				ProviderID: provider.GetID(),
+				Chapters:   chapters,
+			})
+
+			latest := chapters[len(chapters)-1]
+			if latest.Number >= media.Chapters.Latest.LatestChapter {
+				media.Chapters.Latest.LatestChapter = latest.Number
+				media.Chapters.Latest.LatestTitle = latest.Title
+				if latest.UpdatedAt != nil {
+					media.Chapters.Latest.UpdatedAt = *latest.UpdatedAt
+				}
+
+				currentChapter := int(latest.Number)
+				media.CurrentChapter = &currentChapter
+			}
+		}
+	}
+}
+
+// providerMappings converts stored mappings to their event form.
+func providerMappings(mappings []types.Mapping) []events.ProviderMapping {
+	found := make([]events.ProviderMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		found = append(found, events.ProviderMapping{
+			ProviderID: mapping.ProviderID,
+			ID:         mapping.ID,
+			Similarity: mapping.Similarity,
+		})
 	}
+	return found
 }
 
-func clean(s string) string {
-	return strings.TrimSpace(strings.ToLower(s))
+// getProviderType looks up the type of the provider that produced a mapping.
+func getProviderType(providerId string, type_ types.Type) *string {
+	if type_ == types.TypeAnime {
+		for _, provider := range *providers.GetAnimeProviders() {
+			if provider.GetID() == providerId {
+				providerType := string(provider.GetType())
+				return &providerType
+			}
+		}
+	} else {
+		for _, provider := range *providers.GetMangaProviders() {
+			if provider.GetID() == providerId {
+				providerType := string(provider.GetType())
+				return &providerType
+			}
+		}
+	}
+
+	return nil
 }
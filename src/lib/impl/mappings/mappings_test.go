@@ -0,0 +1,227 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/lib/impl/request/requesttest"
+	"anify/eltik/go/src/mappings/impl/manga"
+	"anify/eltik/go/src/types"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeRepository keeps media, overrides and match reports in memory. Methods the
+// mapping pipeline does not use panic through the nil embedded Repository.
+type fakeRepository struct {
+	database.Repository
+
+	mu        sync.Mutex
+	media     map[string]types.Media
+	overrides []database.MappingOverride
+	reports   []types.MatchReport
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{media: make(map[string]types.Media)}
+}
+
+func (r *fakeRepository) Get(ctx context.Context, id string, type_ types.Type) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media, ok := r.media[id]
+	if !ok || media.Type != type_ {
+		return nil, nil
+	}
+	if type_ == types.TypeAnime {
+		anime := media.ToAnime()
+		return &anime, nil
+	}
+	manga := media.ToManga()
+	return &manga, nil
+}
+
+func (r *fakeRepository) GetAnimeByID(ctx context.Context, id string) (*types.Anime, error) {
+	stored, err := r.Get(ctx, id, types.TypeAnime)
+	if stored == nil || err != nil {
+		return nil, err
+	}
+	return stored.(*types.Anime), nil
+}
+
+func (r *fakeRepository) GetMangaByID(ctx context.Context, id string) (*types.Manga, error) {
+	stored, err := r.Get(ctx, id, types.TypeManga)
+	if stored == nil || err != nil {
+		return nil, err
+	}
+	return stored.(*types.Manga), nil
+}
+
+// InsertMedia stores the media with its overrides applied, as the Postgres
+// repository does.
+func (r *fakeRepository) InsertMedia(ctx context.Context, media types.Media) error {
+	overrides, _ := r.ListMappingOverrides(ctx, media.ID, media.Type)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	media.Mappings, _ = database.ApplyMappingOverrides(media.Mappings, overrides, func(providerId string) *string {
+		providerType := string(media.Type)
+		return &providerType
+	})
+	r.media[media.ID] = media
+	return nil
+}
+
+func (r *fakeRepository) SaveMatchReport(ctx context.Context, report types.MatchReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func (r *fakeRepository) ListMappingOverrides(ctx context.Context, mediaID string, type_ types.Type) ([]database.MappingOverride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var overrides []database.MappingOverride
+	for _, override := range r.overrides {
+		if (mediaID == "" || override.MediaID == mediaID) && (type_ == "" || override.Type == type_) {
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides, nil
+}
+
+func loadOnePiece(t *testing.T, repo *fakeRepository) []types.Manga {
+	t.Helper()
+
+	_, manga, err := LoadMappings(context.Background(), repo, struct {
+		ID      string
+		Type    types.Type
+		Formats []types.Format
+	}{ID: requesttest.OnePiece, Type: types.TypeManga, Formats: []types.Format{types.FormatManga}})
+	if err != nil {
+		t.Fatalf("LoadMappings: %v", err)
+	}
+	return manga
+}
+
+func TestLoadMappings(t *testing.T) {
+	requesttest.UseCassette(t, "load_mappings.json")
+
+	repo := newFakeRepository()
+	manga := loadOnePiece(t, repo)
+
+	if len(manga) != 1 || manga[0].ID != requesttest.OnePiece {
+		t.Fatalf("LoadMappings returned %d manga", len(manga))
+	}
+
+	stored, ok := repo.media[requesttest.OnePiece]
+	if !ok {
+		t.Fatal("the entry was not stored")
+	}
+	if len(stored.Mappings) != 1 || stored.Mappings[0].ProviderID != "mangadex" || stored.Mappings[0].ID != requesttest.OnePiece {
+		t.Fatalf("mappings = %+v, want mangadex/%s", stored.Mappings, requesttest.OnePiece)
+	}
+	if stored.Mappings[0].Similarity < 0.9 {
+		t.Errorf("similarity = %v", stored.Mappings[0].Similarity)
+	}
+
+	// Information providers and chapters ran as well.
+	if len(stored.Artwork) == 0 {
+		t.Error("no artwork was merged from the information provider")
+	}
+	if stored.Rating["mangadex"] == 0 || stored.AverageRating == nil {
+		t.Errorf("rating = %v, average = %v", stored.Rating, stored.AverageRating)
+	}
+	if len(stored.Chapters.Data) != 1 || len(stored.Chapters.Data[0].Chapters) == 0 {
+		t.Fatalf("chapters = %+v", stored.Chapters.Data)
+	}
+	if stored.CurrentChapter == nil || *stored.CurrentChapter == 0 {
+		t.Errorf("current chapter = %v", stored.CurrentChapter)
+	}
+
+	if len(repo.reports) != 1 {
+		t.Fatalf("saved %d match reports, want 1", len(repo.reports))
+	}
+	report := repo.reports[0]
+	if report.Outcome != types.MatchOutcomeMapped || report.Mapped != 1 || len(report.Searches) == 0 {
+		t.Errorf("report = %s, %d mapped, %d searches", report.Outcome, report.Mapped, len(report.Searches))
+	}
+
+	// Mapping a stored entry again returns it without searching.
+	if again := loadOnePiece(t, repo); len(again) != 1 || len(repo.reports) != 1 {
+		t.Errorf("second load returned %d manga and saved %d reports", len(again), len(repo.reports))
+	}
+}
+
+// TestLoadMappingsMergedFormats maps a job whose formats were merged from several
+// requests, where the format the providers support is not the first one.
+func TestLoadMappingsMergedFormats(t *testing.T) {
+	requesttest.UseCassette(t, "load_mappings.json")
+
+	repo := newFakeRepository()
+	_, manga, err := LoadMappings(context.Background(), repo, struct {
+		ID      string
+		Type    types.Type
+		Formats []types.Format
+	}{ID: requesttest.OnePiece, Type: types.TypeManga, Formats: []types.Format{types.FormatNovel, types.FormatManga}})
+	if err != nil {
+		t.Fatalf("LoadMappings: %v", err)
+	}
+
+	if len(manga) != 1 {
+		t.Fatalf("LoadMappings returned %d manga, want 1", len(manga))
+	}
+	if mappings := repo.media[requesttest.OnePiece].Mappings; len(mappings) != 1 || mappings[0].ProviderID != "mangadex" {
+		t.Errorf("mappings = %+v, want mangadex", mappings)
+	}
+	if report := repo.reports[0]; report.BaseProvider != "mangadex" || report.Outcome != types.MatchOutcomeMapped {
+		t.Errorf("report base provider = %q, outcome = %s", report.BaseProvider, report.Outcome)
+	}
+}
+
+func TestSearchMediaMissingTitles(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("title"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	provider := manga.NewMangaDexProvider()
+	provider.Id = "mangadex-test"
+	provider.RateLimit = 0
+	provider.NeedsProxy = false
+	provider.Api = server.URL
+
+	// A manhwa with only a romaji title and no year, as MangaDex often returns.
+	romaji := "Na Honjaman Level Up"
+	searches := searchMedia(types.MediaInfo{
+		Title:    types.Title{Romaji: &romaji},
+		Synonyms: []string{"Solo Leveling"},
+		Format:   types.FormatManga,
+	}, types.MappingsProviders{MangaProviders: []types.MangaProvider{provider}})
+
+	var searched []string
+	for _, search := range searches {
+		if search.err != nil {
+			t.Errorf("searching %q: %v", search.query, search.err)
+		}
+		searched = append(searched, search.query)
+	}
+	want := []string{romaji, "Solo Leveling"}
+	if !reflect.DeepEqual(searched, want) {
+		t.Errorf("searched %v, want %v", searched, want)
+	}
+	// Each title is searched on two pages.
+	if len(queries) != 4 {
+		t.Errorf("sent %d requests, want 4", len(queries))
+	}
+}
@@ -0,0 +1,392 @@
+package mappings
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Matcher scores how similar two titles are, from 0 (nothing in common) to 1
+// (identical). Titles are cleaned before they are compared.
+type Matcher interface {
+	Name() string
+	Compare(first, second string) float64
+}
+
+// DiceMatcher is the Sørensen–Dice coefficient of the titles' bigrams, the original
+// comparison used by the pipeline. See CompareTwoStrings.
+type DiceMatcher struct{}
+
+func (DiceMatcher) Name() string { return "dice" }
+
+func (DiceMatcher) Compare(first, second string) float64 {
+	return CompareTwoStrings(first, second)
+}
+
+// JaroWinklerMatcher is the Jaro similarity with a bonus for a shared prefix. It
+// favours titles that start the same way, such as a title and its subtitled form.
+type JaroWinklerMatcher struct {
+	// PrefixScale is how much each shared leading character (up to 4) adds. It
+	// defaults to 0.1 and should not exceed 0.25.
+	PrefixScale float64
+}
+
+func (JaroWinklerMatcher) Name() string { return "jaro-winkler" }
+
+func (m JaroWinklerMatcher) Compare(first, second string) float64 {
+	a, b := []rune(first), []rune(second)
+	if string(a) == string(b) {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	window := max(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+	matches := 0
+	for i := range a {
+		for j := max(0, i-window); j < min(len(b), i+window+1); j++ {
+			if matchedB[j] || a[i] != b[j] {
+				continue
+			}
+			matchedA[i], matchedB[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range a {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if a[i] != b[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m64 := float64(matches)
+	jaro := (m64/float64(len(a)) + m64/float64(len(b)) + (m64-float64(transpositions)/2)/m64) / 3
+
+	prefix := 0
+	for prefix < min(4, len(a), len(b)) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	scale := m.PrefixScale
+	if scale == 0 {
+		scale = 0.1
+	}
+	return jaro + float64(prefix)*scale*(1-jaro)
+}
+
+// TokenSetMatcher compares the sets of words in the titles, ignoring their order
+// and duplicates. A title whose words are all contained in the other scores 1, which
+// suits providers that append extra words such as the author or "(Official)".
+type TokenSetMatcher struct{}
+
+func (TokenSetMatcher) Name() string { return "token-set" }
+
+func (TokenSetMatcher) Compare(first, second string) float64 {
+	a, b := tokenSet(first), tokenSet(second)
+	if len(a) == 0 || len(b) == 0 {
+		if len(a) == len(b) {
+			return 1
+		}
+		return 0
+	}
+
+	var shared, onlyA, onlyB []string
+	for token := range a {
+		if b[token] {
+			shared = append(shared, token)
+		} else {
+			onlyA = append(onlyA, token)
+		}
+	}
+	for token := range b {
+		if !a[token] {
+			onlyB = append(onlyB, token)
+		}
+	}
+	sort.Strings(shared)
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+
+	base := strings.Join(shared, " ")
+	withA := strings.TrimSpace(base + " " + strings.Join(onlyA, " "))
+	withB := strings.TrimSpace(base + " " + strings.Join(onlyB, " "))
+
+	score := levenshteinSimilarity(withA, withB)
+	if base != "" {
+		score = max(score, levenshteinSimilarity(base, withA), levenshteinSimilarity(base, withB))
+	}
+	return score
+}
+
+// tokenSet splits a title into its distinct words.
+func tokenSet(title string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range strings.Fields(title) {
+		tokens[token] = true
+	}
+	return tokens
+}
+
+// LevenshteinMatcher is one minus the edit distance divided by the length of the
+// longer title.
+type LevenshteinMatcher struct{}
+
+func (LevenshteinMatcher) Name() string { return "levenshtein" }
+
+func (LevenshteinMatcher) Compare(first, second string) float64 {
+	return levenshteinSimilarity(first, second)
+}
+
+func levenshteinSimilarity(first, second string) float64 {
+	a, b := []rune(first), []rune(second)
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein returns the number of single rune insertions, deletions and
+// substitutions needed to turn a into b.
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// WeightedMatcher is a matcher and its weight in an EnsembleMatcher.
+type WeightedMatcher struct {
+	Matcher Matcher
+	Weight  float64
+}
+
+// EnsembleMatcher is the weighted mean of several matchers' scores.
+type EnsembleMatcher struct {
+	Matchers []WeightedMatcher
+}
+
+func (EnsembleMatcher) Name() string { return "ensemble" }
+
+func (m EnsembleMatcher) Compare(first, second string) float64 {
+	total, weights := 0.0, 0.0
+	for _, weighted := range m.Matchers {
+		if weighted.Weight <= 0 {
+			continue
+		}
+		total += weighted.Weight * weighted.Matcher.Compare(first, second)
+		weights += weighted.Weight
+	}
+
+	if weights == 0 {
+		return 0
+	}
+	return total / weights
+}
+
+// DefaultEnsemble combines every matcher, leaning on Dice and token sets.
+var DefaultEnsemble = EnsembleMatcher{
+	Matchers: []WeightedMatcher{
+		{Matcher: DiceMatcher{}, Weight: 0.4},
+		{Matcher: TokenSetMatcher{}, Weight: 0.25},
+		{Matcher: JaroWinklerMatcher{}, Weight: 0.2},
+		{Matcher: LevenshteinMatcher{}, Weight: 0.15},
+	},
+}
+
+// MatchConfig controls how a provider's search results are matched to the base
+// provider's titles.
+type MatchConfig struct {
+	Matcher Matcher
+	// MinRating is the score the best matching title needs for the result to be
+	// considered at all.
+	MinRating float64
+	// MinSimilarity is the score the chosen result's title needs against the base
+	// provider's title and synonyms to be mapped.
+	MinSimilarity float64
+}
+
+// DefaultMatchConfig is used for providers without their own config. LoadMatchConfigs
+// replaces it when given a "default" entry.
+var DefaultMatchConfig = MatchConfig{
+	Matcher:       DiceMatcher{},
+	MinRating:     0.7,
+	MinSimilarity: 0.4,
+}
+
+var (
+	matchConfigsMu sync.Mutex
+	matchConfigs   = make(map[string]MatchConfig)
+)
+
+// SetMatchConfig overrides the matching config of a provider.
+func SetMatchConfig(providerId string, config MatchConfig) {
+	matchConfigsMu.Lock()
+	defer matchConfigsMu.Unlock()
+
+	matchConfigs[providerId] = config
+}
+
+// GetMatchConfig returns the matching config of a provider, falling back to
+// DefaultMatchConfig. A config without a matcher uses DiceMatcher.
+func GetMatchConfig(providerId string) MatchConfig {
+	matchConfigsMu.Lock()
+	defer matchConfigsMu.Unlock()
+
+	config, ok := matchConfigs[providerId]
+	if !ok {
+		config = DefaultMatchConfig
+	}
+	if config.Matcher == nil {
+		config.Matcher = DiceMatcher{}
+	}
+	return config
+}
+
+// MatcherByName returns the matcher with the given Name. "ensemble" is
+// DefaultEnsemble.
+func MatcherByName(name string) (Matcher, bool) {
+	for _, matcher := range []Matcher{DiceMatcher{}, JaroWinklerMatcher{}, TokenSetMatcher{}, LevenshteinMatcher{}, DefaultEnsemble} {
+		if matcher.Name() == name {
+			return matcher, true
+		}
+	}
+	return nil, false
+}
+
+// ParseMatchConfigs parses a comma separated list of provider configs, each written
+// as provider=matcher[:minRating[:minSimilarity]], such as
+// "mangadex=ensemble:0.65:0.4". A threshold that is left out keeps the default. The
+// "default" provider sets the config of every provider without its own, and its
+// thresholds are what the other entries leave out.
+func ParseMatchConfigs(value string) (map[string]MatchConfig, error) {
+	entries := make(map[string]string)
+	var providerIds []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		providerId, spec, ok := strings.Cut(entry, "=")
+		providerId = strings.TrimSpace(providerId)
+		if !ok || providerId == "" {
+			return nil, fmt.Errorf("invalid match config %q, expected provider=matcher", entry)
+		}
+		if _, ok := entries[providerId]; !ok {
+			providerIds = append(providerIds, providerId)
+		}
+		entries[providerId] = spec
+	}
+
+	base := DefaultMatchConfig
+	configs := make(map[string]MatchConfig)
+	if spec, ok := entries["default"]; ok {
+		config, err := parseMatchConfig(base, "default", spec)
+		if err != nil {
+			return nil, err
+		}
+		base = config
+		configs["default"] = config
+	}
+
+	for _, providerId := range providerIds {
+		if providerId == "default" {
+			continue
+		}
+		config, err := parseMatchConfig(base, providerId, entries[providerId])
+		if err != nil {
+			return nil, err
+		}
+		configs[providerId] = config
+	}
+
+	return configs, nil
+}
+
+// parseMatchConfig parses matcher[:minRating[:minSimilarity]], taking the
+// thresholds that are left out from base.
+func parseMatchConfig(base MatchConfig, providerId string, spec string) (MatchConfig, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 {
+		return MatchConfig{}, fmt.Errorf("invalid match config %q, expected provider=matcher[:minRating[:minSimilarity]]", providerId+"="+spec)
+	}
+
+	config := base
+	matcher, ok := MatcherByName(strings.TrimSpace(parts[0]))
+	if !ok {
+		return MatchConfig{}, fmt.Errorf("unknown matcher %q for %s", strings.TrimSpace(parts[0]), providerId)
+	}
+	config.Matcher = matcher
+
+	thresholds := []*float64{&config.MinRating, &config.MinSimilarity}
+	for i, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		threshold, err := strconv.ParseFloat(part, 64)
+		if err != nil || threshold < 0 || threshold > 1 {
+			return MatchConfig{}, fmt.Errorf("invalid threshold %q for %s, expected a number between 0 and 1", part, providerId)
+		}
+		*thresholds[i] = threshold
+	}
+
+	return config, nil
+}
+
+// LoadMatchConfigs parses value with ParseMatchConfigs and applies the configs. It
+// is called at startup with MATCH_CONFIG.
+func LoadMatchConfigs(value string) error {
+	configs, err := ParseMatchConfigs(value)
+	if err != nil {
+		return err
+	}
+
+	for providerId, config := range configs {
+		if providerId == "default" {
+			matchConfigsMu.Lock()
+			DefaultMatchConfig = config
+			matchConfigsMu.Unlock()
+			continue
+		}
+		SetMatchConfig(providerId, config)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package mappings
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	// Reference values from Winkler's paper and the usual implementations.
+	tests := []struct {
+		first, second string
+		want          float64
+	}{
+		{"martha", "marhta", 0.961111},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.813333},
+		{"crate", "trace", 0.733333},
+		{"jellyfish", "smellyfish", 0.896296},
+		{"abc", "abc", 1},
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+		{"", "", 1},
+	}
+
+	for _, tt := range tests {
+		if got := (JaroWinklerMatcher{}).Compare(tt.first, tt.second); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("JaroWinkler(%q, %q) = %v, want %v", tt.first, tt.second, got, tt.want)
+		}
+	}
+
+	// A larger prefix scale rewards the shared "mar" more.
+	if got := (JaroWinklerMatcher{PrefixScale: 0.2}).Compare("martha", "marhta"); math.Abs(got-0.977778) > 1e-6 {
+		t.Errorf("JaroWinkler with prefix scale 0.2 = %v, want 0.977778", got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		first, second string
+		distance      int
+		want          float64
+	}{
+		{"kitten", "sitting", 3, 1 - 3.0/7},
+		{"flaw", "lawn", 2, 0.5},
+		{"saturday", "sunday", 3, 1 - 3.0/8},
+		{"gumbo", "gambol", 2, 1 - 2.0/6},
+		{"ワンピース", "ワンピ", 2, 1 - 2.0/5},
+		{"abc", "", 3, 0},
+		{"", "", 0, 1},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.first), []rune(tt.second)); got != tt.distance {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.first, tt.second, got, tt.distance)
+		}
+		if got := (LevenshteinMatcher{}).Compare(tt.first, tt.second); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Levenshtein(%q, %q) = %v, want %v", tt.first, tt.second, got, tt.want)
+		}
+	}
+}
+
+func TestTokenSet(t *testing.T) {
+	tests := []struct {
+		first, second string
+		want          float64
+	}{
+		// The fuzzywuzzy token_set_ratio examples.
+		{"fuzzy was a bear", "fuzzy fuzzy was a bear", 1},
+		{"one piece", "piece one", 1},
+		// Every word of one title is in the other.
+		{"attack on titan", "attack on titan season 2", 1},
+		{"abc", "xyz", 0},
+		// "c" is shared, and "c a" and "c b" differ in one of 3 runes.
+		{"c a", "b c", 2.0 / 3},
+		{"", "", 1},
+		{"", "abc", 0},
+	}
+
+	for _, tt := range tests {
+		if got := (TokenSetMatcher{}).Compare(tt.first, tt.second); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TokenSet(%q, %q) = %v, want %v", tt.first, tt.second, got, tt.want)
+		}
+	}
+
+	// "one piece" is shared. "one piece film" and "one piece movie" are 5 edits out
+	// of 15 runes apart, which beats either of them against "one piece".
+	if got := (TokenSetMatcher{}).Compare("one piece film", "piece one movie"); math.Abs(got-(1-5.0/15)) > 1e-9 {
+		t.Errorf("TokenSet with extra words = %v, want %v", got, 1-5.0/15)
+	}
+}
+
+// fixedMatcher always returns the same score.
+type fixedMatcher float64
+
+func (fixedMatcher) Name() string { return "fixed" }
+
+func (m fixedMatcher) Compare(first, second string) float64 { return float64(m) }
+
+func TestEnsemble(t *testing.T) {
+	tests := []struct {
+		name     string
+		matchers []WeightedMatcher
+		want     float64
+	}{
+		{"weighted mean", []WeightedMatcher{{fixedMatcher(1), 3}, {fixedMatcher(0), 1}}, 0.75},
+		{"zero weights ignored", []WeightedMatcher{{fixedMatcher(0.5), 1}, {fixedMatcher(1), 0}, {fixedMatcher(1), -1}}, 0.5},
+		{"no matchers", nil, 0},
+	}
+
+	for _, tt := range tests {
+		if got := (EnsembleMatcher{Matchers: tt.matchers}).Compare("a", "b"); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Ensemble = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// DefaultEnsemble agrees with every matcher on identical and disjoint titles.
+	if got := DefaultEnsemble.Compare("one piece", "one piece"); got != 1 {
+		t.Errorf("DefaultEnsemble of identical titles = %v, want 1", got)
+	}
+	if got := DefaultEnsemble.Compare("abc", "xyz"); got != 0 {
+		t.Errorf("DefaultEnsemble of disjoint titles = %v, want 0", got)
+	}
+
+	// "martha" and "marhta": Dice 0.4, token set and Levenshtein 4/6, Jaro-Winkler
+	// 0.961111.
+	want := 0.4*0.4 + 0.25*(4.0/6) + 0.2*0.961111 + 0.15*(4.0/6)
+	if got := DefaultEnsemble.Compare("martha", "marhta"); math.Abs(got-want) > 1e-6 {
+		t.Errorf("DefaultEnsemble(martha, marhta) = %v, want %v", got, want)
+	}
+}
+
+func TestParseMatchConfigs(t *testing.T) {
+	configs, err := ParseMatchConfigs(" mangadex = ensemble:0.65:0.5 , other=jaro-winkler::0.3, default=token-set:0.8")
+	if err != nil {
+		t.Fatalf("ParseMatchConfigs: %v", err)
+	}
+
+	want := map[string]struct {
+		matcher                  string
+		minRating, minSimilarity float64
+	}{
+		"mangadex": {"ensemble", 0.65, 0.5},
+		// Thresholds that are left out come from the default entry.
+		"other":   {"jaro-winkler", 0.8, 0.3},
+		"default": {"token-set", 0.8, DefaultMatchConfig.MinSimilarity},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("got %d configs, want %d", len(configs), len(want))
+	}
+	for providerId, w := range want {
+		config := configs[providerId]
+		if config.Matcher == nil || config.Matcher.Name() != w.matcher || config.MinRating != w.minRating || config.MinSimilarity != w.minSimilarity {
+			t.Errorf("%s = %+v, want %+v", providerId, config, w)
+		}
+	}
+
+	if configs, err := ParseMatchConfigs(""); err != nil || len(configs) != 0 {
+		t.Errorf("empty value = %v, %v", configs, err)
+	}
+
+	for _, value := range []string{"mangadex", "=dice", "mangadex=cosine", "mangadex=dice:1.5", "mangadex=dice:x", "mangadex=dice:0.1:0.2:0.3"} {
+		if _, err := ParseMatchConfigs(value); err == nil {
+			t.Errorf("ParseMatchConfigs(%q) returned no error", value)
+		}
+	}
+}
+
+func TestLoadMatchConfigs(t *testing.T) {
+	defaults := DefaultMatchConfig
+	t.Cleanup(func() {
+		matchConfigsMu.Lock()
+		DefaultMatchConfig = defaults
+		delete(matchConfigs, "load-test")
+		matchConfigsMu.Unlock()
+	})
+
+	if err := LoadMatchConfigs("load-test=levenshtein:0.9,default=jaro-winkler"); err != nil {
+		t.Fatalf("LoadMatchConfigs: %v", err)
+	}
+
+	if config := GetMatchConfig("load-test"); config.Matcher.Name() != "levenshtein" || config.MinRating != 0.9 {
+		t.Errorf("load-test = %+v", config)
+	}
+	if config := GetMatchConfig("unconfigured"); config.Matcher.Name() != "jaro-winkler" {
+		t.Errorf("unconfigured provider uses %s, want the default jaro-winkler", config.Matcher.Name())
+	}
+}
@@ -0,0 +1,147 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+)
+
+// overridePlan is the mapping overrides of one ID, grouped the way the pipeline
+// applies them.
+type overridePlan struct {
+	// pins maps a provider ID to its pinned override.
+	pins map[string]database.MappingOverride
+	// none holds the providers marked as having no match.
+	none map[string]bool
+	// blocked holds blocked pairings as "providerId/providerMediaId".
+	blocked map[string]bool
+	// order keeps the pinned and "none" providers in the order they were listed.
+	order []database.MappingOverride
+}
+
+func newOverridePlan(overrides []database.MappingOverride) overridePlan {
+	plan := overridePlan{
+		pins:    make(map[string]database.MappingOverride),
+		none:    make(map[string]bool),
+		blocked: make(map[string]bool),
+	}
+
+	for _, override := range overrides {
+		switch override.Kind {
+		case database.OverridePin:
+			plan.pins[override.ProviderID] = override
+			plan.order = append(plan.order, override)
+		case database.OverrideNone:
+			plan.none[override.ProviderID] = true
+			plan.order = append(plan.order, override)
+		case database.OverrideBlock:
+			plan.blocked[blockKey(override.ProviderID, override.ProviderMediaID)] = true
+		}
+	}
+
+	return plan
+}
+
+func blockKey(providerId string, id string) string {
+	return providerId + "/" + id
+}
+
+// skips reports whether fuzzy matching is skipped for a provider because it is
+// pinned or marked as having no match.
+func (p overridePlan) skips(providerId string) bool {
+	_, pinned := p.pins[providerId]
+	return pinned || p.none[providerId]
+}
+
+// isBlocked reports whether a provider's ID may not be mapped.
+func (p overridePlan) isBlocked(providerId string, id string) bool {
+	return p.blocked[blockKey(providerId, id)]
+}
+
+// filterProviders removes the providers that are pinned or marked as having no
+// match, so they are not searched.
+func (p overridePlan) filterProviders(suitable types.MappingsProviders) types.MappingsProviders {
+	var filtered types.MappingsProviders
+	for _, provider := range suitable.AnimeProviders {
+		if !p.skips(provider.GetID()) {
+			filtered.AnimeProviders = append(filtered.AnimeProviders, provider)
+		}
+	}
+	for _, provider := range suitable.MangaProviders {
+		if !p.skips(provider.GetID()) {
+			filtered.MangaProviders = append(filtered.MangaProviders, provider)
+		}
+	}
+	return filtered
+}
+
+// reports returns a search report for each pinned or "none" provider, explaining
+// why it was not searched.
+func (p overridePlan) reports() []types.SearchReport {
+	reports := make([]types.SearchReport, 0, len(p.order))
+	for _, override := range p.order {
+		report := types.SearchReport{
+			ProviderID: override.ProviderID,
+			Override:   string(override.Kind),
+			Candidates: []types.CandidateReport{},
+		}
+		if override.Kind == database.OverridePin {
+			similarity := 1.0
+			report.Candidates = append(report.Candidates, types.CandidateReport{
+				ID:         override.ProviderMediaID,
+				Rating:     1,
+				Similarity: &similarity,
+				Rule:       types.MatchPinned,
+			})
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// ApplyOverrides applies the mapping overrides of a stored entry to its mappings:
+// pinned providers are mapped to the pinned ID, and mappings of providers marked as
+// having no match or of blocked pairings are removed. Manga chapters are fetched
+// again when the mappings changed. It returns nil when the entry is not stored.
+func ApplyOverrides(ctx context.Context, repo database.Repository, id string, type_ types.Type) (*types.Media, error) {
+	var media types.Media
+	switch type_ {
+	case types.TypeAnime:
+		anime, err := repo.GetAnimeByID(ctx, id)
+		if err != nil || anime == nil {
+			return nil, err
+		}
+		media = anime.ToMedia()
+	default:
+		manga, err := repo.GetMangaByID(ctx, id)
+		if err != nil || manga == nil {
+			return nil, err
+		}
+		media = manga.ToMedia()
+	}
+
+	overrides, err := repo.ListMappingOverrides(ctx, id, type_)
+	if err != nil {
+		return nil, err
+	}
+	mappings, changed := database.ApplyMappingOverrides(media.Mappings, overrides, func(providerId string) *string {
+		return getProviderType(providerId, media.Type)
+	})
+
+	if !changed {
+		return &media, nil
+	}
+
+	media.Mappings = mappings
+	if media.Type == types.TypeManga {
+		media.Chapters = types.ChapterCollection{}
+		media.CurrentChapter = nil
+		loadChapters(&media)
+	}
+
+	if err := repo.InsertMedia(ctx, media); err != nil {
+		return nil, err
+	}
+
+	return &media, nil
+}
@@ -0,0 +1,167 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/lib/impl/request/requesttest"
+	"anify/eltik/go/src/mappings/impl/manga"
+	"anify/eltik/go/src/types"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func mangaProvider(id string) types.MangaProvider {
+	provider := manga.NewMangaDexProvider()
+	provider.Id = id
+	return provider
+}
+
+func TestFilterProviders(t *testing.T) {
+	plan := newOverridePlan([]database.MappingOverride{
+		{ProviderID: "pinned", ProviderMediaID: "1", Kind: database.OverridePin},
+		{ProviderID: "none", Kind: database.OverrideNone},
+		{ProviderID: "blocked", ProviderMediaID: "2", Kind: database.OverrideBlock},
+	})
+
+	filtered := plan.filterProviders(types.MappingsProviders{
+		MangaProviders: []types.MangaProvider{mangaProvider("pinned"), mangaProvider("none"), mangaProvider("blocked"), mangaProvider("other")},
+	})
+
+	// A block only stops one ID from being mapped, so the provider is still searched.
+	var ids []string
+	for _, provider := range filtered.MangaProviders {
+		ids = append(ids, provider.GetID())
+	}
+	if want := []string{"blocked", "other"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("searched providers = %v, want %v", ids, want)
+	}
+	if len(filtered.AnimeProviders) != 0 {
+		t.Errorf("got %d anime providers, want 0", len(filtered.AnimeProviders))
+	}
+
+	if !plan.isBlocked("blocked", "2") || plan.isBlocked("blocked", "3") || plan.isBlocked("other", "2") {
+		t.Error("isBlocked does not match only the blocked pairing")
+	}
+
+	reports := plan.reports()
+	if len(reports) != 2 || reports[0].Override != "pin" || reports[1].Override != "none" {
+		t.Fatalf("reports = %+v, want the pin and the none", reports)
+	}
+	if len(reports[0].Candidates) != 1 || reports[0].Candidates[0].ID != "1" || reports[0].Candidates[0].Rule != types.MatchPinned {
+		t.Errorf("pin report candidates = %+v", reports[0].Candidates)
+	}
+}
+
+func TestMatchSearchBlocked(t *testing.T) {
+	title, year := "One Piece", 1997
+	baseData := types.MediaInfo{Title: types.Title{English: &title}, Format: types.FormatManga, Year: &year}
+	search := providerSearch{
+		providerId: "mangadex",
+		query:      title,
+		results: []types.Result{
+			{ID: requesttest.OnePiece, Title: "One Piece", Year: 1997, Format: types.FormatManga, ProviderId: "mangadex"},
+			{ID: "party", Title: "One Piece Party", Year: 2011, Format: types.FormatManga, ProviderId: "mangadex"},
+		},
+	}
+	baseTitles := []MatchTitle{{Title: title}}
+
+	mapped, report := matchSearch(search, baseData, title, baseTitles, nil, newOverridePlan(nil))
+	if mapped == nil || mapped.Data.ID != requesttest.OnePiece || report.Candidates[0].Rule != types.MatchAccepted {
+		t.Fatalf("without overrides: mapped = %+v, candidates = %+v", mapped, report.Candidates)
+	}
+
+	plan := newOverridePlan([]database.MappingOverride{{ProviderID: "mangadex", ProviderMediaID: requesttest.OnePiece, Kind: database.OverrideBlock}})
+	mapped, report = matchSearch(search, baseData, title, baseTitles, nil, plan)
+	if mapped != nil {
+		t.Errorf("the blocked ID was mapped: %+v", mapped)
+	}
+	if report.Candidates[0].Rule != types.MatchBlocked || report.Candidates[1].Rule != types.MatchNotBest {
+		t.Errorf("rules = %s, %s, want %s, %s", report.Candidates[0].Rule, report.Candidates[1].Rule, types.MatchBlocked, types.MatchNotBest)
+	}
+}
+
+func TestLoadMappingsOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		override database.MappingOverride
+	}{
+		{"none", database.MappingOverride{ProviderID: "mangadex", Kind: database.OverrideNone}},
+		{"block", database.MappingOverride{ProviderID: "mangadex", ProviderMediaID: requesttest.OnePiece, Kind: database.OverrideBlock}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requesttest.UseCassette(t, "load_mappings.json")
+
+			override := tt.override
+			override.MediaID, override.Type = requesttest.OnePiece, types.TypeManga
+			repo := newFakeRepository()
+			repo.overrides = []database.MappingOverride{override}
+
+			if manga := loadOnePiece(t, repo); len(manga) != 0 {
+				t.Errorf("LoadMappings returned %d manga, want none", len(manga))
+			}
+			if len(repo.media) != 0 {
+				t.Error("an entry without mappings was stored")
+			}
+			if len(repo.reports) != 1 || repo.reports[0].Outcome != types.MatchOutcomeNoMappings {
+				t.Fatalf("reports = %+v, want one without mappings", repo.reports)
+			}
+
+			rules := make(map[string]bool)
+			for _, search := range repo.reports[0].Searches {
+				if search.Override != "" {
+					rules["override:"+search.Override] = true
+				}
+				for _, candidate := range search.Candidates {
+					rules[candidate.Rule] = true
+				}
+			}
+			switch tt.name {
+			case "none":
+				if !rules["override:none"] || len(rules) != 1 {
+					t.Errorf("report rules = %v, want only the none override", rules)
+				}
+			case "block":
+				if !rules[types.MatchBlocked] {
+					t.Errorf("report rules = %v, want %s", rules, types.MatchBlocked)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	repo := newFakeRepository()
+	repo.media["anime-id"] = types.Media{
+		ID:   "anime-id",
+		Type: types.TypeAnime,
+		Mappings: []types.Mapping{
+			{ID: "wrong", ProviderID: "pinned", Similarity: 0.8},
+			{ID: "bad", ProviderID: "blocked", Similarity: 0.9},
+			{ID: "good", ProviderID: "kept", Similarity: 0.95},
+		},
+	}
+	repo.overrides = []database.MappingOverride{
+		{MediaID: "anime-id", Type: types.TypeAnime, ProviderID: "pinned", ProviderMediaID: "right", Kind: database.OverridePin},
+		{MediaID: "anime-id", Type: types.TypeAnime, ProviderID: "blocked", ProviderMediaID: "bad", Kind: database.OverrideBlock},
+		{MediaID: "anime-id", Type: types.TypeAnime, ProviderID: "new", ProviderMediaID: "added", Kind: database.OverridePin},
+	}
+
+	media, err := ApplyOverrides(context.Background(), repo, "anime-id", types.TypeAnime)
+	if err != nil || media == nil {
+		t.Fatalf("ApplyOverrides = %v, %v", media, err)
+	}
+
+	var got []string
+	for _, mapping := range repo.media["anime-id"].Mappings {
+		got = append(got, mapping.ProviderID+"/"+mapping.ID)
+	}
+	if want := []string{"kept/good", "pinned/right", "new/added"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored mappings = %v, want %v", got, want)
+	}
+
+	if media, err := ApplyOverrides(context.Background(), repo, "missing", types.TypeAnime); media != nil || err != nil {
+		t.Errorf("ApplyOverrides of a missing entry = %v, %v, want nil", media, err)
+	}
+}
@@ -0,0 +1,161 @@
+package mappings
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Subtitle separators such as "Title: Subtitle", "Title - Subtitle" or
+	// "Title ~Subtitle~" are replaced by a space.
+	romajiSeparators = regexp.MustCompile(`\s*[:~\-–—|/]+\s*`)
+
+	// Macrons and circumflexes mark long vowels, which are written as a single
+	// vowel after normalization.
+	romajiMacrons = strings.NewReplacer(
+		"ā", "a", "ē", "e", "ī", "i", "ō", "o", "ū", "u",
+		"â", "a", "ê", "e", "î", "i", "ô", "o", "û", "u",
+		"×", "x",
+	)
+
+	// Kunrei-shiki and Nihon-shiki spellings are rewritten to Hepburn. The identity
+	// pairs come first so Hepburn syllables are not rewritten a second time.
+	romajiHepburn = strings.NewReplacer(
+		"shi", "shi", "chi", "chi", "tsu", "tsu",
+		"sha", "sha", "shu", "shu", "sho", "sho",
+		"cha", "cha", "chu", "chu", "cho", "cho",
+		"sya", "sha", "syu", "shu", "syo", "sho",
+		"tya", "cha", "tyu", "chu", "tyo", "cho",
+		"zya", "ja", "zyu", "ju", "zyo", "jo",
+		"jya", "ja", "jyu", "ju", "jyo", "jo",
+		"si", "shi", "ti", "chi", "tu", "tsu",
+		"hu", "fu", "zi", "ji", "di", "ji", "du", "zu",
+	)
+
+	// Long vowels written out as "ou", "oo", "uu" and so on become a single vowel.
+	romajiLongVowels = strings.NewReplacer(
+		"ou", "o", "oo", "o", "uu", "u", "aa", "a", "ee", "e", "ii", "i",
+	)
+	// "oh" is a long "o" when no vowel follows, as in "Satoh" or "Ohtani".
+	romajiLongOh = regexp.MustCompile(`oh([^aeiouy]|$)`)
+	// Hepburn writes "m" before "b" and "p" ("shimbun"), other systems "n".
+	romajiSyllabicM = regexp.MustCompile(`m([bp])`)
+
+	// The particles を, は and へ are written "wo", "ha" and "he" by some sources.
+	romajiParticles = strings.NewReplacer(" wo ", " o ", " ha ", " wa ", " he ", " e ")
+
+	romajiOrdinalSeason = regexp.MustCompile(`\b(\d+)(?:st|nd|rd|th) (season|part)\b`)
+	romajiWordSeason    = regexp.MustCompile(`\b(second|third|fourth|fifth|sixth) season\b`)
+	romajiShortSeason   = regexp.MustCompile(`\bs(\d+)\b`)
+	romajiRomanSeason   = regexp.MustCompile(`\b(season|part) (ii|iii|iv|v|vi|vii|viii|ix|x)\b`)
+	romajiRomanSuffix   = regexp.MustCompile(` (ii|iii|iv|vi|vii|viii|ix)$`)
+	romajiNumberSuffix  = regexp.MustCompile(`(\S+) ([2-9])$`)
+	romajiFirstSeason   = regexp.MustCompile(`\b(season|part) 1\b`)
+	romajiSpaces        = regexp.MustCompile(`\s+`)
+	romajiInstallment   = regexp.MustCompile(`\b(?:season|part) \d+\b`)
+)
+
+// sequelPenalty scales the score of two titles that name different seasons or parts.
+const sequelPenalty = 0.5
+
+var seasonWords = map[string]string{
+	"second": "2", "third": "3", "fourth": "4", "fifth": "5", "sixth": "6",
+}
+
+var romanNumerals = map[string]int{
+	"ii": 2, "iii": 3, "iv": 4, "v": 5, "vi": 6, "vii": 7, "viii": 8, "ix": 9, "x": 10,
+}
+
+// numberWords are words after which a trailing number is part of the title, as in
+// "Kaiju No. 8", rather than a season.
+var numberWords = map[string]bool{
+	"no": true, "no.": true, "season": true, "part": true, "vol": true, "vol.": true, "volume": true, "#": true,
+}
+
+// NormalizeRomaji rewrites a lowercased romaji title so different romanizations of
+// the same Japanese title compare equal. On top of NormalizeTitle, long vowels ("ō",
+// "ou", "oo", "oh") become a single vowel, Kunrei-shiki spellings become Hepburn,
+// and the particles "wo", "ha" and "he" are written as pronounced. These rules would
+// mangle English, so they are only meant for romaji titles.
+func NormalizeRomaji(title string) string {
+	title = NormalizeTitle(title)
+
+	title = romajiHepburn.Replace(title)
+	title = romajiLongVowels.Replace(title)
+	title = romajiLongOh.ReplaceAllString(title, "o$1")
+	title = romajiSyllabicM.ReplaceAllString(title, "n$1")
+	title = strings.TrimSpace(romajiParticles.Replace(" " + title + " "))
+
+	return romajiSpaces.ReplaceAllString(title, " ")
+}
+
+// NormalizeTitle rewrites the parts of a lowercased title that are written
+// differently whatever its language. Subtitle separators become spaces and macrons
+// are dropped.
+//
+// Season and part suffixes are kept but written one way: "2nd Season", "Second
+// Season", "S2", a trailing "II" and a trailing "2" all become "season 2", and "Part
+// II" becomes "part 2". A sequel therefore still differs from the original, while
+// "Season 1" and "Part 1" are dropped as they name the original.
+func NormalizeTitle(title string) string {
+	title = romajiSeparators.ReplaceAllString(title, " ")
+	title = romajiMacrons.Replace(title)
+	title = romajiSpaces.ReplaceAllString(strings.TrimSpace(title), " ")
+
+	title = romajiOrdinalSeason.ReplaceAllString(title, "$2 $1")
+	title = romajiWordSeason.ReplaceAllStringFunc(title, func(match string) string {
+		return "season " + seasonWords[strings.Fields(match)[0]]
+	})
+	title = romajiShortSeason.ReplaceAllString(title, "season $1")
+	title = romajiRomanSeason.ReplaceAllStringFunc(title, func(match string) string {
+		fields := strings.Fields(match)
+		return fields[0] + " " + strconv.Itoa(romanNumerals[fields[1]])
+	})
+	title = romajiRomanSuffix.ReplaceAllStringFunc(title, func(match string) string {
+		return " season " + strconv.Itoa(romanNumerals[strings.TrimSpace(match)])
+	})
+	title = romajiNumberSuffix.ReplaceAllStringFunc(title, func(match string) string {
+		word, number, _ := strings.Cut(match, " ")
+		if numberWords[word] {
+			return match
+		}
+		return word + " season " + number
+	})
+	title = romajiFirstSeason.ReplaceAllString(title, "")
+
+	return romajiSpaces.ReplaceAllString(strings.TrimSpace(title), " ")
+}
+
+// installment returns the seasons and parts a cleaned title names, such as "season
+// 2", or "" for the original.
+func installment(title string) string {
+	return strings.Join(romajiInstallment.FindAllString(title, -1), " ")
+}
+
+// preparedTitle is a cleaned title and the seasons or parts it names, worked out
+// once so they are not redone for every pair of titles compared.
+type preparedTitle struct {
+	text        string
+	installment string
+}
+
+// prepareTitle cleans a title with CleanRomaji when romaji is set, or Clean.
+func prepareTitle(title string, romaji bool) preparedTitle {
+	text := Clean(title)
+	if romaji {
+		text = CleanRomaji(title)
+	}
+	return preparedTitle{text: text, installment: installment(text)}
+}
+
+// compareTitles scores two titles with the matcher. The score is scaled by
+// sequelPenalty when the titles name different seasons or parts, so a sequel is not
+// mapped to the original even when the rest of the title is the same.
+func compareTitles(matcher Matcher, first, second preparedTitle) float64 {
+	score := matcher.Compare(first.text, second.text)
+	if score > 0 && first.installment != second.installment {
+		score *= sequelPenalty
+	}
+	return score
+}
@@ -0,0 +1,155 @@
+package mappings
+
+import (
+	"anify/eltik/go/src/types"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRomaji(t *testing.T) {
+	tests := []struct {
+		name, title, want string
+	}{
+		{"macrons", "tōkyō revengers", "tokyo revengers"},
+		{"circumflexes", "tôkyô revengers", "tokyo revengers"},
+		{"ou", "toukyou revengers", "tokyo revengers"},
+		{"oo and ou", "ookami to koushinryou", "okami to koshinryo"},
+		{"oh", "satoh", "sato"},
+		{"oh before a vowel", "ohayou", "ohayo"},
+		{"kunrei", "tuki ga kirei desu ne", "tsuki ga kirei desu ne"},
+		{"nihon-shiki", "hujisan", "fujisan"},
+		{"syllabic m", "shimbun", "shinbun"},
+		{"wo", "kono subarashii sekai ni shukufuku wo", "kono subarashi sekai ni shukufuku o"},
+		{"ha", "sora ha aoi", "sora wa aoi"},
+		{"he", "tokyo he", "tokyo e"},
+		{"colon", "re:zero kara hajimeru isekai seikatsu", "re zero kara hajimeru isekai seikatsu"},
+		{"dash", "kimetsu no yaiba - yuukaku hen", "kimetsu no yaiba yukaku hen"},
+		{"tildes", "kaguya-sama ~love is war~", "kaguya sama love is war"},
+		{"2nd season", "shingeki no kyojin 2nd season", "shingeki no kyojin season 2"},
+		{"second season", "shingeki no kyojin second season", "shingeki no kyojin season 2"},
+		{"s2", "overlord s2", "overlord season 2"},
+		{"season ii", "overlord season ii", "overlord season 2"},
+		{"trailing ii", "overlord ii", "overlord season 2"},
+		{"trailing iv", "overlord iv", "overlord season 4"},
+		{"trailing number", "made in abyss 2", "made in abyss season 2"},
+		{"part 2", "jojo no kimyou na bouken part 2", "jojo no kimyo na boken part 2"},
+		{"part ii", "jojo no kimyou na bouken part ii", "jojo no kimyo na boken part 2"},
+		{"2nd part", "vinland saga 2nd part", "vinland saga part 2"},
+		{"season 1", "spy x family season 1", "spy x family"},
+		{"number in the title", "kaijuu no. 8", "kaiju no. 8"},
+		{"large number", "mob psycho 100", "mob psycho 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeRomaji(tt.title); got != tt.want {
+				t.Errorf("NormalizeRomaji(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	// English titles keep their spelling; only separators and seasons change.
+	tests := []struct {
+		title, want string
+	}{
+		{"attack on titan", "attack on titan"},
+		{"hunter x hunter", "hunter x hunter"},
+		{"the book of he", "the book of he"},
+		{"the dangers in my heart: season ii", "the dangers in my heart season 2"},
+		{"my hero academia 2nd season", "my hero academia season 2"},
+		{"my hero academia 2", "my hero academia season 2"},
+		{"steins;gate 0", "steins;gate 0"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	if got := Clean("Attack on Titan: The Final Season"); got != "attack on titan the final season" {
+		t.Errorf("Clean = %q", got)
+	}
+	if got := CleanRomaji("Shingeki no Kyoujin: The Final Season"); got != "shingeki no kyojin the final season" {
+		t.Errorf("CleanRomaji = %q", got)
+	}
+	if got := CleanRomaji("ＯＶＥＲＬＯＲＤ Ⅱ"); got != "overlord season 2" {
+		t.Errorf("CleanRomaji of full-width letters = %q", got)
+	}
+}
+
+func TestSimilarityWith(t *testing.T) {
+	tests := []struct {
+		name       string
+		title      string
+		baseTitles []MatchTitle
+		want       float64
+	}{
+		{"english", "Attack on Titan", []MatchTitle{{Title: "Attack on Titan"}}, 1},
+		// The base title is cleaned the same way as the result's title.
+		{"romaji spellings", "Shingeki no Kyoujin", []MatchTitle{{Title: "Shingeki no Kyojin", Romaji: true}}, 1},
+		{"romaji seasons", "Overlord 2", []MatchTitle{{Title: "Overlord II", Romaji: true}}, 1},
+		{"best base title", "Attack on Titan", []MatchTitle{{Title: "Shingeki no Kyojin", Romaji: true}, {Title: "Attack on Titan"}}, 1},
+		// "overlord" and "overlord season 2" share 7 of 22 bigrams, halved.
+		{"sequel", "Overlord II", []MatchTitle{{Title: "Overlord", Romaji: true}}, (14.0 / 21) / 2},
+		{"empty base title", "Overlord", []MatchTitle{{Title: ""}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SimilarityWith(DiceMatcher{}, tt.title, tt.baseTitles)
+			if diff := got.Value - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("SimilarityWith(%q) = %v, want %v", tt.title, got.Value, tt.want)
+			}
+			if got.Same != (tt.want > 0.6) {
+				t.Errorf("SimilarityWith(%q).Same = %v", tt.title, got.Same)
+			}
+		})
+	}
+}
+
+func TestFindBestMatch2DArrayWith(t *testing.T) {
+	baseTitles := []MatchTitle{{Title: "Attack on Titan"}, {Title: "Shingeki no Kyojin", Romaji: true}}
+	results := [][]string{
+		{"Shingeki no Kyojin Season 2", "Attack on Titan Season 2"},
+		{"Shingeki no Kyoujin", "AoT"},
+	}
+
+	result := FindBestMatch2DArrayWith(DiceMatcher{}, baseTitles, results)
+	if result.BestMatchIndex != 1 || result.BestMatch.Target != "Shingeki no Kyoujin" || result.BestMatch.Rating != 1 {
+		t.Errorf("best match = %d %+v, want the original", result.BestMatchIndex, result.BestMatch)
+	}
+	if len(result.Candidates) != 2 || result.Candidates[0].Rating > 0.5 {
+		t.Errorf("candidates = %+v, want the sequel penalized", result.Candidates)
+	}
+}
+
+func TestBaseMatchTitles(t *testing.T) {
+	english, romaji := "Soul Eater", "Souru Ītā"
+	titles := baseMatchTitles(types.MediaInfo{
+		Title:    types.Title{English: &english, Romaji: &romaji},
+		Synonyms: []string{"The House of Souls", ""},
+	})
+
+	want := []MatchTitle{
+		{Title: "Soul Eater"},
+		{Title: "Souru Ītā", Romaji: true},
+		{Title: "The House of Souls"},
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("baseMatchTitles = %+v, want %+v", titles, want)
+	}
+
+	// An English synonym keeps its spelling, where the romaji rules would turn
+	// "house" into "hose" and "souls" into "sols".
+	if got := prepareTitle(titles[2].Title, titles[2].Romaji).text; got != "the house of souls" {
+		t.Errorf("synonym cleaned to %q", got)
+	}
+	if got := prepareTitle(titles[1].Title, titles[1].Romaji).text; got != "soru ita" {
+		t.Errorf("romaji title cleaned to %q", got)
+	}
+}
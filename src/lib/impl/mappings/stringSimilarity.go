@@ -16,6 +16,9 @@ type StringResult struct {
 	Ratings        []Rating
 	BestMatch      Rating
 	BestMatchIndex int
+	// Candidates is only set by FindBestMatch2DArray. It holds the best rating of
+	// each target array over every main string, in the order of the arrays.
+	Candidates []Rating
 }
 
 type SimilarityResult struct {
@@ -23,18 +26,46 @@ type SimilarityResult struct {
 	Value float64
 }
 
-func Similarity(externalTitle, title string, titleArray []string) SimilarityResult {
-	if title == "" {
-		title = ""
+// MatchTitle is a base title that search results are matched against. Romaji
+// titles are cleaned with CleanRomaji, and so is whatever they are compared with;
+// other titles are cleaned with Clean.
+type MatchTitle struct {
+	Title  string
+	Romaji bool
+}
+
+// plainTitles returns the titles as MatchTitles that are not romaji.
+func plainTitles(titles []string) []MatchTitle {
+	matchTitles := make([]MatchTitle, len(titles))
+	for i, title := range titles {
+		matchTitles[i] = MatchTitle{Title: title}
 	}
-	simi := CompareTwoStrings(Clean(strings.ToLower(title)), strings.ToLower(externalTitle))
-	for _, el := range titleArray {
-		if el != "" {
-			tempSimi := CompareTwoStrings(strings.ToLower(title), strings.ToLower(el))
-			if tempSimi > simi {
-				simi = tempSimi
-			}
+	return matchTitles
+}
+
+// Similarity scores title against externalTitle and every title in titleArray, none
+// of which are treated as romaji.
+func Similarity(externalTitle, title string, titleArray []string) SimilarityResult {
+	return SimilarityWith(DiceMatcher{}, title, plainTitles(append([]string{externalTitle}, titleArray...)))
+}
+
+// SimilarityWith returns the best score of title against the base titles with the
+// given matcher. Both sides of every comparison are cleaned the same way, and titles
+// that name different seasons or parts score lower, see compareTitles.
+func SimilarityWith(matcher Matcher, title string, baseTitles []MatchTitle) SimilarityResult {
+	prepared := [2]preparedTitle{prepareTitle(title, false), prepareTitle(title, true)}
+
+	simi := 0.0
+	for _, base := range baseTitles {
+		if base.Title == "" {
+			continue
 		}
+
+		other := prepared[0]
+		if base.Romaji {
+			other = prepared[1]
+		}
+		simi = max(simi, compareTitles(matcher, prepareTitle(base.Title, base.Romaji), other))
 	}
 
 	found := simi > 0.6
@@ -91,23 +122,56 @@ func FindBestMatchArray(mainStrings, targetStrings []string) StringResult {
 	return mainStringResults[overallBestMatchIndex]
 }
 
-// FindBestMatch2DArray finds the best match from a 2D target string array.
+// FindBestMatch2DArray finds the best match from a 2D target string array. None of
+// the main strings are treated as romaji.
 func FindBestMatch2DArray(mainStrings []string, targetStrings [][]string) StringResult {
+	return FindBestMatch2DArrayWith(DiceMatcher{}, plainTitles(mainStrings), targetStrings)
+}
+
+// FindBestMatch2DArrayWith is FindBestMatch2DArray for base titles, using the given
+// matcher instead of Dice. Titles that name different seasons or parts score lower,
+// see compareTitles.
+func FindBestMatch2DArrayWith(matcher Matcher, mainTitles []MatchTitle, targetStrings [][]string) StringResult {
 	overallBestMatch := StringResult{
 		Ratings:        []Rating{},
 		BestMatch:      Rating{Target: "", Rating: 0},
 		BestMatchIndex: 0,
 	}
+	candidates := make([]Rating, len(targetStrings))
+
+	mains := make([]preparedTitle, len(mainTitles))
+	for i, main := range mainTitles {
+		mains[i] = prepareTitle(main.Title, main.Romaji)
+	}
+
+	// Every target is cleaned both ways, as it is compared with romaji and other
+	// main titles alike.
+	targets := make([][][2]preparedTitle, len(targetStrings))
+	for i, targetArray := range targetStrings {
+		targets[i] = make([][2]preparedTitle, len(targetArray))
+		for j, targetString := range targetArray {
+			targets[i][j] = [2]preparedTitle{prepareTitle(targetString, false), prepareTitle(targetString, true)}
+		}
+	}
+
+	for mainIndex, main := range mains {
+		romaji := 0
+		if mainTitles[mainIndex].Romaji {
+			romaji = 1
+		}
 
-	for _, mainString := range mainStrings {
 		for targetArrayIndex, targetArray := range targetStrings {
 			ratings := []Rating{}
 
-			for _, targetString := range targetArray {
-				currentRating := CompareTwoStrings(Clean(strings.ToLower(strings.TrimSpace(mainString))), Clean(strings.ToLower(strings.TrimSpace(targetString))))
+			for i, targetString := range targetArray {
+				currentRating := compareTitles(matcher, main, targets[targetArrayIndex][i][romaji])
 				ratings = append(ratings, Rating{Target: targetString, Rating: currentRating})
 			}
 
+			if len(ratings) == 0 {
+				continue
+			}
+
 			bestMatchIndex := 0
 			for i, r := range ratings {
 				if r.Rating > ratings[bestMatchIndex].Rating {
@@ -115,6 +179,10 @@ func FindBestMatch2DArray(mainStrings []string, targetStrings [][]string) String
 				}
 			}
 
+			if candidates[targetArrayIndex].Target == "" || ratings[bestMatchIndex].Rating > candidates[targetArrayIndex].Rating {
+				candidates[targetArrayIndex] = ratings[bestMatchIndex]
+			}
+
 			if ratings[bestMatchIndex].Rating > overallBestMatch.BestMatch.Rating {
 				overallBestMatch = StringResult{
 					Ratings:        ratings,
@@ -125,61 +193,71 @@ func FindBestMatch2DArray(mainStrings []string, targetStrings [][]string) String
 		}
 	}
 
+	overallBestMatch.Candidates = candidates
 	return overallBestMatch
 }
 
-// CompareTwoStrings calculates similarity between two strings using bigrams.
+// CompareTwoStrings calculates similarity between two strings using bigrams. The
+// bigrams are made of runes, so titles in any script are compared by character.
+// When both strings are Chinese, Japanese or Korean, CompareCJK is used instead.
 func CompareTwoStrings(first, second string) float64 {
-	first = strings.ReplaceAll(first, " ", "")
-	second = strings.ReplaceAll(second, " ", "")
+	if IsCJK(first) && IsCJK(second) {
+		return CompareCJK(first, second)
+	}
+
+	a := []rune(strings.ReplaceAll(first, " ", ""))
+	b := []rune(strings.ReplaceAll(second, " ", ""))
 
-	if first == second {
+	if string(a) == string(b) {
 		return 1 // identical or empty
 	}
-	if len(first) < 2 || len(second) < 2 {
+	if len(a) < 2 || len(b) < 2 {
 		return 0 // if either is a 0-letter or 1-letter string
 	}
 
-	firstBigrams := make(map[string]int)
-	for i := 0; i < len(first)-1; i++ {
-		bigram := first[i : i+2]
-		firstBigrams[bigram]++
-	}
-
-	intersectionSize := 0
-	for i := 0; i < len(second)-1; i++ {
-		bigram := second[i : i+2]
-		if count, exists := firstBigrams[bigram]; exists && count > 0 {
-			firstBigrams[bigram]--
-			intersectionSize++
-		}
-	}
-
-	return 2.0 * float64(intersectionSize) / float64(len(first)+len(second)-2)
+	return dice(ngrams(a, 2), ngrams(b, 2))
 }
 
-// Clean prepares a string for comparison by removing unnecessary characters and terms.
+// Clean prepares a string for comparison by normalizing it, writing seasons and
+// parts one way and removing unnecessary characters. See CleanRomaji for romaji
+// titles.
 func Clean(title string) string {
+	title = Normalize(title)
+	title = NormalizeTitle(title)
+	title = RemoveSpecialChars(title)
+	title = TransformSpecificVariations(title)
+	return title
+}
+
+// CleanRomaji is Clean for romaji titles, which also writes their spelling one way
+// with NormalizeRomaji.
+func CleanRomaji(title string) string {
+	title = Normalize(title)
+	title = NormalizeRomaji(title)
 	title = RemoveSpecialChars(title)
 	title = TransformSpecificVariations(title)
 	return title
 }
 
-// RemoveSpecialChars removes special characters from a string.
+var (
+	specialChars   = regexp.MustCompile(`[^\p{L}\p{N}\p{M}!@#$%^&*()\-= ]`)
+	punctuation    = regexp.MustCompile(`[^\p{L}\p{N}\p{M}\-= ]`)
+	repeatedSpaces = regexp.MustCompile(` {2,}`)
+)
+
+// RemoveSpecialChars removes special characters from a string. Letters, numbers and
+// combining marks of every script are kept, so native titles survive.
 func RemoveSpecialChars(title string) string {
-	re := regexp.MustCompile(`[^A-Za-z0-9!@#$%^&*()\-= ]`)
-	title = re.ReplaceAllString(title, " ")
-	re2 := regexp.MustCompile(`[^A-Za-z0-9\-= ]`)
-	title = re2.ReplaceAllString(title, "")
-	title = strings.ReplaceAll(title, "  ", " ")
+	title = specialChars.ReplaceAllString(title, " ")
+	title = punctuation.ReplaceAllString(title, "")
+	title = repeatedSpaces.ReplaceAllString(title, " ")
 	return title
 }
 
-// TransformSpecificVariations standardizes specific variations in the text.
+// TransformSpecificVariations standardizes specific variations in the text. Romaji
+// spellings are handled by NormalizeRomaji, so only surrounding whitespace is left.
 func TransformSpecificVariations(title string) string {
-	title = strings.ReplaceAll(title, "yuu", "yu")
-	title = strings.ReplaceAll(title, " ou", " oh")
-	return title
+	return strings.TrimSpace(title)
 }
 
 func Slugify(args ...interface{}) string {
@@ -0,0 +1,111 @@
+package mappings
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize prepares a title for comparison across scripts. NFKC folds full-width
+// Latin letters and half-width katakana into their usual forms, the result is
+// lowercased, and hiragana is folded into katakana so the same reading written in
+// either script compares equal.
+func Normalize(title string) string {
+	title = norm.NFKC.String(title)
+	title = strings.ToLower(title)
+	return strings.Map(foldKana, title)
+}
+
+// foldKana maps a hiragana rune to the matching katakana and leaves others alone.
+func foldKana(r rune) rune {
+	switch {
+	case r >= 'ぁ' && r <= 'ゖ':
+		return r + ('ァ' - 'ぁ')
+	case r == 'ゝ' || r == 'ゞ':
+		return r + ('ヽ' - 'ゝ')
+	}
+	return r
+}
+
+// isCJK reports whether the rune is a Han, kana or Hangul character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー'
+}
+
+// IsCJK reports whether most of the title's letters are Chinese, Japanese or Korean.
+func IsCJK(title string) bool {
+	cjk, letters := 0, 0
+	for _, r := range title {
+		if !unicode.IsLetter(r) && r != 'ー' {
+			continue
+		}
+		letters++
+		if isCJK(r) {
+			cjk++
+		}
+	}
+	return letters > 0 && cjk*2 >= letters
+}
+
+// CompareCJK scores two Chinese, Japanese or Korean titles between 0 and 1. Words in
+// these scripts are not separated by spaces and single characters carry meaning, so
+// the score is the mean of the Dice coefficients of the shared characters and of the
+// shared character pairs. Anything that is not a letter or number is ignored.
+func CompareCJK(first, second string) float64 {
+	a := cjkRunes(Normalize(first))
+	b := cjkRunes(Normalize(second))
+
+	if string(a) == string(b) {
+		return 1
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	unigrams := dice(ngrams(a, 1), ngrams(b, 1))
+	if len(a) < 2 || len(b) < 2 {
+		return unigrams / 2
+	}
+
+	return (unigrams + dice(ngrams(a, 2), ngrams(b, 2))) / 2
+}
+
+// cjkRunes returns the letters and numbers of the title.
+func cjkRunes(title string) []rune {
+	var runes []rune
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == 'ー' {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// ngrams counts every run of n runes.
+func ngrams(runes []rune, n int) map[string]int {
+	grams := make(map[string]int)
+	for i := 0; i+n <= len(runes); i++ {
+		grams[string(runes[i:i+n])]++
+	}
+	return grams
+}
+
+// dice returns the Sørensen–Dice coefficient of two multisets.
+func dice(first, second map[string]int) float64 {
+	total, intersection := 0, 0
+	for gram, count := range first {
+		total += count
+		if other := second[gram]; other > 0 {
+			intersection += min(count, other)
+		}
+	}
+	for _, count := range second {
+		total += count
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(intersection) / float64(total)
+}
@@ -0,0 +1,92 @@
+package mappings
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name, title, want string
+	}{
+		{"katakana", "ワンピース", "ワンピース"},
+		{"hiragana", "わんぴーす", "ワンピース"},
+		{"iteration mark", "いすゞ", "イスヾ"},
+		{"half-width katakana", "ﾜﾝﾋﾟｰｽ", "ワンピース"},
+		{"full-width latin", "ＯＮＥ　ＰＩＥＣＥ", "one piece"},
+		{"full-width digits", "ドラゴンボールＺ２", "ドラゴンボールz2"},
+		{"kanji", "進撃の巨人", "進撃ノ巨人"},
+		{"hangul", "원피스", "원피스"},
+		{"latin", "Attack on Titan", "attack on titan"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.title); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareCJK(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second string
+		want          float64
+	}{
+		{"identical kanji", "進撃の巨人", "進撃の巨人", 1},
+		{"hiragana and katakana", "しんげきのきょじん", "シンゲキノキョジン", 1},
+		{"half-width and full-width katakana", "ﾜﾝﾋﾟｰｽ", "ワンピース", 1},
+		{"punctuation ignored", "鬼滅の刃・", "鬼滅の刃", 1},
+		// 5 of 13 characters and 4 of 11 pairs are shared.
+		{"kanji prefix", "進撃の巨人", "進撃の巨人 第2期", (10.0/13 + 8.0/11) / 2},
+		{"identical hangul", "원피스", "원피스", 1},
+		{"different hangul", "원피스", "나루토", 0},
+		{"mixed script", "ドラゴンボールZ", "ドラゴンボールＺ", 1},
+		{"latin and kana", "one piece", "ワンピース", 0},
+		// Too short for pairs, so only half of the score is available.
+		{"single rune", "火", "火影", (2.0 / 3) / 2},
+		{"no letters", "！？", "火", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareCJK(tt.first, tt.second); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CompareCJK(%q, %q) = %v, want %v", tt.first, tt.second, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareTwoStrings(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second string
+		want          float64
+	}{
+		{"identical", "one piece", "one piece", 1},
+		{"spaces ignored", "one piece", "onepiece", 1},
+		// ni ig gh ht and na ac ch ht share one bigram.
+		{"one shared bigram", "night", "nacht", 0.25},
+		{"accented runes", "café", "cafe", 4.0 / 6},
+		{"no shared bigrams", "abc", "xyz", 0},
+		{"single rune", "a", "ab", 0},
+		{"single multi-byte rune", "é", "éa", 0},
+		{"same single rune", "a", "a", 1},
+		{"empty", "", "", 1},
+		{"empty and text", "", "ab", 0},
+		{"kanji use CompareCJK", "火", "火影", (2.0 / 3) / 2},
+		{"kana folding", "わんぴーす", "ワンピース", 1},
+		{"latin and kana", "ONE PIECE", "ワンピース", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareTwoStrings(tt.first, tt.second); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CompareTwoStrings(%q, %q) = %v, want %v", tt.first, tt.second, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	events "anify/eltik/go/src/lib"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Health is what we know about how a proxy has performed for a provider.
+type Health struct {
+	IP                  string        `json:"ip"`
+	ProviderID          string        `json:"providerId"`
+	Successes           int           `json:"successes"`
+	Failures            int           `json:"failures"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	LastStatus          int           `json:"lastStatus"`
+	LastError           string        `json:"lastError,omitempty"`
+	Latency             time.Duration `json:"latency"`
+	LastUsed            time.Time     `json:"lastUsed"`
+	Bans                int           `json:"bans"`
+	BannedUntil         time.Time     `json:"bannedUntil"`
+}
+
+const (
+	// BanThreshold is how many failures in a row get a proxy banned.
+	BanThreshold = 3
+	// BanDuration is how long the first ban lasts. Each further ban doubles it, up
+	// to MaxBanDuration.
+	BanDuration    = 5 * time.Minute
+	MaxBanDuration = 2 * time.Hour
+)
+
+type healthKey struct {
+	providerId string
+	ip         string
+}
+
+var (
+	healthMu sync.Mutex
+	health   = make(map[healthKey]*Health)
+
+	// now is replaced in tests to move bans along without waiting.
+	now = time.Now
+)
+
+func getHealth(providerId, ip string) *Health {
+	key := healthKey{providerId: providerId, ip: ip}
+	h, ok := health[key]
+	if !ok {
+		h = &Health{IP: ip, ProviderID: providerId}
+		health[key] = h
+	}
+	return h
+}
+
+// IsFailureStatus reports whether a status code means the proxy, rather than the
+// request, is the problem: forbidden, rate limited or a server error.
+func IsFailureStatus(status int) bool {
+	return status == http.StatusForbidden || status == http.StatusTooManyRequests || status >= 500
+}
+
+// ReportSuccess records a request through the proxy that got a usable response.
+func ReportSuccess(providerId, ip string, latency time.Duration, status int) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	h := getHealth(providerId, ip)
+	h.Successes++
+	h.ConsecutiveFailures = 0
+	h.LastStatus = status
+	h.LastError = ""
+	h.LastUsed = now()
+	h.Latency = averageLatency(h.Latency, latency)
+}
+
+// ReportFailure records a connection error or a failure status from the proxy. The
+// proxy is banned once it fails BanThreshold times in a row. It returns true when
+// this failure caused a ban, in which case a ProxyBanned event is published.
+func ReportFailure(providerId, ip string, latency time.Duration, status int, err error) bool {
+	banned, event := recordFailure(providerId, ip, latency, status, err)
+	if banned {
+		// Published outside the lock so subscribers can read the health table.
+		events.Publish(event)
+	}
+	return banned
+}
+
+func recordFailure(providerId, ip string, latency time.Duration, status int, err error) (bool, events.ProxyBanned) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	h := getHealth(providerId, ip)
+	h.Failures++
+	h.ConsecutiveFailures++
+	h.LastStatus = status
+	h.LastError = ""
+	if err != nil {
+		h.LastError = err.Error()
+	}
+	h.LastUsed = now()
+	h.Latency = averageLatency(h.Latency, latency)
+
+	if h.ConsecutiveFailures < BanThreshold || now().Before(h.BannedUntil) {
+		return false, events.ProxyBanned{}
+	}
+
+	duration := BanDuration << h.Bans
+	if duration > MaxBanDuration || duration <= 0 {
+		duration = MaxBanDuration
+	}
+
+	h.Bans++
+	h.BannedUntil = now().Add(duration)
+
+	return true, events.ProxyBanned{
+		ProviderID: providerId,
+		Proxy:      ip,
+		Failures:   h.ConsecutiveFailures,
+		Until:      h.BannedUntil,
+		Status:     status,
+		Error:      h.LastError,
+	}
+}
+
+// IsBanned reports whether the proxy is currently banned for the provider. Once a
+// ban runs out the proxy is given a clean slate of failures.
+func IsBanned(providerId, ip string) bool {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	h, ok := health[healthKey{providerId: providerId, ip: ip}]
+	if !ok || h.BannedUntil.IsZero() {
+		return false
+	}
+
+	if now().Before(h.BannedUntil) {
+		return true
+	}
+
+	h.BannedUntil = time.Time{}
+	h.ConsecutiveFailures = 0
+	return false
+}
+
+// Unbanned filters the proxies that are not banned for the provider.
+func Unbanned(providerId string, proxies []Proxy) []Proxy {
+	var unbanned []Proxy
+	for _, proxy := range proxies {
+		if !IsBanned(providerId, proxy.IP) {
+			unbanned = append(unbanned, proxy)
+		}
+	}
+	return unbanned
+}
+
+// GetHealth returns a snapshot of every proxy used for the provider, or for every
+// provider when providerId is empty, sorted by provider and IP.
+func GetHealth(providerId string) []Health {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	var snapshot []Health
+	for key, h := range health {
+		if providerId == "" || key.providerId == providerId {
+			snapshot = append(snapshot, *h)
+		}
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].ProviderID != snapshot[j].ProviderID {
+			return snapshot[i].ProviderID < snapshot[j].ProviderID
+		}
+		return snapshot[i].IP < snapshot[j].IP
+	})
+
+	return snapshot
+}
+
+// averageLatency keeps an exponentially weighted average so recent requests count
+// more than old ones.
+func averageLatency(current, latest time.Duration) time.Duration {
+	if current == 0 {
+		return latest
+	}
+	return (current*4 + latest) / 5
+}
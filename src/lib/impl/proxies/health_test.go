@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// useFakeNow resets the health table and freezes the clock, returning a function
+// that moves it forward.
+func useFakeNow(t *testing.T) func(time.Duration) {
+	t.Helper()
+
+	current := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	previous := now
+	now = func() time.Time { return current }
+
+	healthMu.Lock()
+	health = make(map[healthKey]*Health)
+	healthMu.Unlock()
+
+	t.Cleanup(func() { now = previous })
+
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+// failTimes reports n failures and returns whether the last one caused a ban.
+func failTimes(providerId, ip string, n int) bool {
+	banned := false
+	for i := 0; i < n; i++ {
+		banned = ReportFailure(providerId, ip, time.Millisecond, http.StatusServiceUnavailable, nil)
+	}
+	return banned
+}
+
+func TestBanAfterThreshold(t *testing.T) {
+	useFakeNow(t)
+
+	if failTimes("p", "ip", BanThreshold-1) {
+		t.Fatal("banned before reaching the threshold")
+	}
+	if IsBanned("p", "ip") {
+		t.Fatal("IsBanned before reaching the threshold")
+	}
+
+	if !ReportFailure("p", "ip", time.Millisecond, 0, errors.New("connection refused")) {
+		t.Fatal("not banned at the threshold")
+	}
+	if !IsBanned("p", "ip") {
+		t.Fatal("IsBanned = false after a ban")
+	}
+
+	// Bans are per provider.
+	if IsBanned("other", "ip") {
+		t.Fatal("ban applied to another provider")
+	}
+
+	h := GetHealth("p")[0]
+	if h.Bans != 1 || h.LastError != "connection refused" || h.Failures != BanThreshold {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	useFakeNow(t)
+
+	failTimes("p", "ip", BanThreshold-1)
+	ReportSuccess("p", "ip", time.Millisecond, http.StatusOK)
+
+	if failTimes("p", "ip", BanThreshold-1) {
+		t.Fatal("banned although a success broke up the failures")
+	}
+}
+
+func TestBanDoublesAndCaps(t *testing.T) {
+	advance := useFakeNow(t)
+
+	expected := BanDuration
+	for ban := 0; ban < 8; ban++ {
+		start := now()
+		if !failTimes("p", "ip", BanThreshold) {
+			t.Fatalf("ban %d: not banned", ban+1)
+		}
+
+		until := GetHealth("p")[0].BannedUntil
+		if got := until.Sub(start); got != expected {
+			t.Fatalf("ban %d lasts %v, want %v", ban+1, got, expected)
+		}
+
+		// Still banned just before the ban runs out, unbanned right after.
+		advance(expected - time.Second)
+		if !IsBanned("p", "ip") {
+			t.Fatalf("ban %d ran out early", ban+1)
+		}
+		advance(time.Second)
+		if IsBanned("p", "ip") {
+			t.Fatalf("ban %d did not run out", ban+1)
+		}
+
+		expected *= 2
+		if expected > MaxBanDuration {
+			expected = MaxBanDuration
+		}
+	}
+}
+
+func TestUnbanGivesCleanSlate(t *testing.T) {
+	advance := useFakeNow(t)
+
+	failTimes("p", "ip", BanThreshold)
+	advance(BanDuration)
+
+	if IsBanned("p", "ip") {
+		t.Fatal("still banned after the ban ran out")
+	}
+	if h := GetHealth("p")[0]; h.ConsecutiveFailures != 0 || !h.BannedUntil.IsZero() {
+		t.Fatalf("health after unban = %+v", h)
+	}
+
+	// A single failure after the ban is not enough for another one.
+	if failTimes("p", "ip", 1) {
+		t.Fatal("banned again after one failure")
+	}
+}
+
+func TestFailuresWhileBannedDoNotExtendBan(t *testing.T) {
+	useFakeNow(t)
+
+	failTimes("p", "ip", BanThreshold)
+	until := GetHealth("p")[0].BannedUntil
+
+	if failTimes("p", "ip", BanThreshold) {
+		t.Fatal("banned again while already banned")
+	}
+	if h := GetHealth("p")[0]; !h.BannedUntil.Equal(until) || h.Bans != 1 {
+		t.Fatalf("health = %+v, want the first ban unchanged", h)
+	}
+}
+
+func TestUnbanned(t *testing.T) {
+	useFakeNow(t)
+
+	failTimes("p", "http://bad", BanThreshold)
+
+	list := Unbanned("p", []Proxy{{IP: "http://bad"}, {IP: "http://good"}})
+	if len(list) != 1 || list[0].IP != "http://good" {
+		t.Fatalf("Unbanned = %v", list)
+	}
+}
+
+func TestIsFailureStatus(t *testing.T) {
+	for status, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusForbidden:           true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+	} {
+		if got := IsFailureStatus(status); got != want {
+			t.Errorf("IsFailureStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/lib/impl/mappings"
+	"anify/eltik/go/src/types"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Config controls the worker pool.
+type Config struct {
+	// Workers is how many jobs run at the same time in this process.
+	Workers int
+	// PollInterval is how long an idle worker waits before checking for jobs again.
+	PollInterval time.Duration
+	// StaleAfter requeues jobs that have been running for longer than this, which
+	// happens when a process stops in the middle of a job.
+	StaleAfter time.Duration
+	// BaseDelay is the wait before retrying a failed job. It doubles for every
+	// attempt up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultConfig = Config{
+	Workers:      2,
+	PollInterval: 2 * time.Second,
+	StaleAfter:   15 * time.Minute,
+	BaseDelay:    30 * time.Second,
+	MaxDelay:     30 * time.Minute,
+}
+
+// Queue runs mapping jobs stored in the database.
+type Queue struct {
+	repo   database.Repository
+	config Config
+}
+
+func New(repo database.Repository, config Config) *Queue {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultConfig.PollInterval
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = DefaultConfig.StaleAfter
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = DefaultConfig.BaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultConfig.MaxDelay
+	}
+
+	return &Queue{repo: repo, config: config}
+}
+
+// Enqueue queues the mapping pipeline for an ID. Formats default to every format
+// of the type.
+func (q *Queue) Enqueue(ctx context.Context, id string, type_ types.Type, formats []types.Format) (database.Job, error) {
+	if len(formats) == 0 {
+		formats = DefaultFormats(type_)
+	}
+
+	return q.repo.EnqueueJob(ctx, id, type_, formats)
+}
+
+// Run starts the workers and blocks until the context is cancelled and every
+// running job has finished.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.requeueStale(ctx)
+	}()
+
+	for i := 0; i < q.config.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			q.work(ctx, worker)
+		}(i + 1)
+	}
+
+	wg.Wait()
+}
+
+// work claims and runs jobs until the context is cancelled.
+func (q *Queue) work(ctx context.Context, worker int) {
+	for ctx.Err() == nil {
+		job, err := q.repo.ClaimJob(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Worker %d failed to claim a job: %v", worker, err)
+			}
+			sleep(ctx, q.config.PollInterval)
+			continue
+		}
+		if job == nil {
+			sleep(ctx, q.config.PollInterval)
+			continue
+		}
+
+		q.process(ctx, worker, *job)
+	}
+}
+
+// process runs the mapping pipeline for a job and records the result. The result is
+// written with a fresh context so a shutdown does not leave the job marked running.
+func (q *Queue) process(ctx context.Context, worker int, job database.Job) {
+	log.Printf("Worker %d running job %d (%s %s, attempt %d/%d)", worker, job.ID, job.Type, job.MediaID, job.Attempts, job.MaxAttempts)
+
+	err := runJob(ctx, q.repo, job)
+
+	writeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err == nil {
+		if err := q.repo.CompleteJob(writeCtx, job.ID); err != nil {
+			log.Printf("Worker %d failed to complete job %d: %v", worker, job.ID, err)
+		}
+		return
+	}
+
+	state, failErr := q.repo.FailJob(writeCtx, job.ID, err, time.Now().Add(q.backoff(job.Attempts)))
+	if failErr != nil {
+		log.Printf("Worker %d failed to record error for job %d: %v", worker, job.ID, failErr)
+		return
+	}
+
+	log.Printf("Worker %d: job %d failed (%s): %v", worker, job.ID, state, err)
+}
+
+// runJob calls LoadMappings, turning a panic into an error so one bad entry cannot
+// stop the worker.
+func runJob(ctx context.Context, repo database.Repository, job database.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	formats := job.Formats
+	if len(formats) == 0 {
+		formats = DefaultFormats(job.Type)
+	}
+
+	_, _, err = mappings.LoadMappings(ctx, repo, struct {
+		ID      string
+		Type    types.Type
+		Formats []types.Format
+	}{
+		ID:      job.MediaID,
+		Type:    job.Type,
+		Formats: formats,
+	})
+	return err
+}
+
+// requeueStale periodically puts abandoned running jobs back in the queue.
+func (q *Queue) requeueStale(ctx context.Context) {
+	for {
+		count, err := q.repo.RequeueStaleJobs(ctx, q.config.StaleAfter)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to requeue stale jobs: %v", err)
+		} else if count > 0 {
+			log.Printf("Requeued %d stale job(s)", count)
+		}
+
+		if !sleep(ctx, q.config.StaleAfter/2) {
+			return
+		}
+	}
+}
+
+// backoff returns the delay before retrying a job that failed on the given attempt.
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.config.BaseDelay
+	for i := 1; i < attempt && delay < q.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > q.config.MaxDelay {
+		delay = q.config.MaxDelay
+	}
+	return delay
+}
+
+// DefaultFormats returns every format of a type.
+func DefaultFormats(type_ types.Type) []types.Format {
+	if type_ == types.TypeAnime {
+		return []types.Format{types.FormatTV, types.FormatTVShort, types.FormatMovie, types.FormatSpecial, types.FormatOVA, types.FormatONA}
+	}
+	return []types.Format{types.FormatManga, types.FormatOneShot, types.FormatNovel}
+}
+
+// sleep waits for d and reports whether the context is still active.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
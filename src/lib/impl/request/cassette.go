@@ -0,0 +1,260 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CassetteMode decides whether a cassette talks to the network.
+type CassetteMode string
+
+const (
+	// CassetteReplay only serves recorded responses and fails on anything else.
+	CassetteReplay CassetteMode = "replay"
+	// CassetteRecord sends every request and overwrites the cassette with the results.
+	CassetteRecord CassetteMode = "record"
+	// CassetteAuto replays what is recorded and records what is missing.
+	CassetteAuto CassetteMode = "auto"
+)
+
+// ErrNoInteraction is returned in replay mode for a request that is not recorded.
+var ErrNoInteraction = errors.New("no recorded interaction for request")
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// Cassette is an http.RoundTripper that records responses to a JSON file and
+// replays them later, so provider code can run without the network.
+type Cassette struct {
+	Path string
+	Mode CassetteMode
+
+	base         http.RoundTripper
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	changed      bool
+}
+
+// CassetteModeFromEnv reads the mode from CASSETTE_MODE and defaults to replay, so
+// `CASSETTE_MODE=record go test ./...` refreshes the recordings.
+func CassetteModeFromEnv() CassetteMode {
+	switch mode := CassetteMode(strings.ToLower(os.Getenv("CASSETTE_MODE"))); mode {
+	case CassetteRecord, CassetteAuto:
+		return mode
+	default:
+		return CassetteReplay
+	}
+}
+
+// NewCassette loads the cassette at path. A missing file is fine outside of replay
+// mode. Requests that are not replayed are sent through base.
+func NewCassette(path string, mode CassetteMode, base http.RoundTripper) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: mode, base: base}
+
+	if mode != CassetteRecord {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &c.interactions); err != nil {
+				return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist) && mode == CassetteAuto:
+		default:
+			return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+		}
+	}
+
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	recorded := RecordedRequest{Method: req.Method, URL: req.URL.String(), Body: string(body)}
+
+	if c.Mode != CassetteRecord {
+		if interaction, ok := c.find(recorded); ok {
+			return interaction.Response.toResponse(req), nil
+		}
+		if c.Mode == CassetteReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, recorded.URL)
+		}
+	}
+
+	resp, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := resp.Header.Clone()
+	headers.Del("Set-Cookie")
+
+	interaction := Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: headers,
+			Body:    string(respBody),
+		},
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+	c.changed = true
+	c.mu.Unlock()
+
+	return interaction.Response.toResponse(req), nil
+}
+
+// find returns the first unused interaction matching the request. Once all of them
+// are used the last match is served again, so repeated calls stay deterministic.
+func (c *Cassette) find(req RecordedRequest) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last := -1
+	for i, interaction := range c.interactions {
+		if interaction.Request != req {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+
+	if last >= 0 {
+		return c.interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+// Save writes the cassette to disk if anything new was recorded.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Path, data, 0o644); err != nil {
+		return err
+	}
+
+	c.changed = false
+	return nil
+}
+
+func (r RecordedResponse) toResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+var (
+	cassetteMu     sync.Mutex
+	activeCassette *Cassette
+)
+
+// UseCassette routes every request through the cassette at path until the returned
+// stop function is called, which saves new recordings and restores the previous
+// client. While a cassette is active, requests skip proxies and Google Translate so
+// recorded URLs stay stable, and replays skip rate limit waits.
+func UseCassette(path string, mode CassetteMode) (func() error, error) {
+	previous := Client()
+
+	cassette, err := NewCassette(path, mode, previous.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	cassetteMu.Lock()
+	if activeCassette != nil {
+		cassetteMu.Unlock()
+		return nil, errors.New("a cassette is already in use")
+	}
+	activeCassette = cassette
+	cassetteMu.Unlock()
+
+	SetClient(&http.Client{
+		Timeout:   previous.Timeout,
+		Transport: cassette,
+	})
+
+	return func() error {
+		SetClient(previous)
+
+		cassetteMu.Lock()
+		activeCassette = nil
+		cassetteMu.Unlock()
+
+		return cassette.Save()
+	}, nil
+}
+
+// currentCassette returns the cassette set by UseCassette, if any.
+func currentCassette() *Cassette {
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+
+	return activeCassette
+}
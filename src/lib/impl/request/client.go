@@ -0,0 +1,167 @@
+package request
+
+import (
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultUserAgent is sent by providers that do not set their own.
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"
+
+// ClientConfig configures the HTTP client shared by every provider request.
+type ClientConfig struct {
+	// Timeout is the limit for a single attempt, including reading the body.
+	Timeout time.Duration
+	// MaxIdleConnsPerHost is how many keep-alive connections are kept per host.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes keep-alive connections that were unused for this long.
+	IdleConnTimeout time.Duration
+}
+
+// DefaultClientConfig is used until Configure is called.
+var DefaultClientConfig = ClientConfig{
+	Timeout:             10 * time.Second,
+	MaxIdleConnsPerHost: 16,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+var (
+	clientMu sync.RWMutex
+	client   = NewClient(DefaultClientConfig)
+
+	userAgentsMu sync.RWMutex
+	userAgents   = make(map[string]string)
+)
+
+// NewClient builds an HTTP client with keep-alive, HTTP/2 and transparent gzip and
+// brotli decoding.
+func NewClient(config ClientConfig) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		// Decoding is done by decodingTransport so brotli is handled as well.
+		DisableCompression: true,
+	}
+
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: &decodingTransport{base: transport},
+	}
+}
+
+// Configure replaces the shared client with one built from config.
+func Configure(config ClientConfig) {
+	SetClient(NewClient(config))
+}
+
+// SetClient replaces the shared client used by Request.
+func SetClient(c *http.Client) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	client = c
+}
+
+// Client returns the shared client used by Request.
+func Client() *http.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+
+	return client
+}
+
+// SetUserAgent sets the User-Agent sent for a provider when the request does not
+// carry one.
+func SetUserAgent(providerId string, userAgent string) {
+	userAgentsMu.Lock()
+	defer userAgentsMu.Unlock()
+
+	userAgents[providerId] = userAgent
+}
+
+// userAgent returns the User-Agent of a provider, falling back to DefaultUserAgent.
+func userAgent(providerId string) string {
+	userAgentsMu.RLock()
+	defer userAgentsMu.RUnlock()
+
+	if ua, ok := userAgents[providerId]; ok && ua != "" {
+		return ua
+	}
+	return DefaultUserAgent
+}
+
+// decodingTransport asks for gzip and brotli responses and decodes them, so callers
+// always read a plain body.
+type decodingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" {
+		// The caller wants to handle the encoding itself.
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip, br")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.ReadCloser
+	switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+	case "gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		body = &decodedBody{Reader: reader, closers: []io.Closer{reader, resp.Body}}
+	case "br":
+		body = &decodedBody{Reader: brotli.NewReader(resp.Body), closers: []io.Closer{resp.Body}}
+	default:
+		return resp, nil
+	}
+
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decodedBody reads from a decompressor and closes it along with the raw body.
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var first error
+	for _, c := range b.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
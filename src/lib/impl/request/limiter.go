@@ -0,0 +1,167 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// limiter spaces out the requests of a single provider and holds them back while
+// the provider has told us to slow down.
+type limiter struct {
+	mu           sync.Mutex
+	interval     time.Duration
+	next         time.Time
+	blockedUntil time.Time
+}
+
+// defaultRetryAfter is how long a provider is held back after a 429 without any
+// retry headers.
+const defaultRetryAfter = 5 * time.Second
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*limiter)
+)
+
+func getLimiter(providerId string) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	l, ok := limiters[providerId]
+	if !ok {
+		l = &limiter{}
+		limiters[providerId] = l
+	}
+	return l
+}
+
+// SetRateLimit sets the minimum delay between two requests to a provider. Calling it
+// again only changes the delay, so it is safe to call before every request.
+func SetRateLimit(providerId string, interval time.Duration) {
+	l := getLimiter(providerId)
+
+	l.mu.Lock()
+	l.interval = interval
+	l.mu.Unlock()
+}
+
+// clock is the time source of the limiters. Tests replace it with a fake one.
+type clock interface {
+	Now() time.Time
+	// After returns a channel that receives once d has passed, and a function that
+	// stops the wait.
+	After(d time.Duration) (<-chan time.Time, func())
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) (<-chan time.Time, func()) {
+	timer := time.NewTimer(d)
+	return timer.C, func() { timer.Stop() }
+}
+
+var limiterClock clock = realClock{}
+
+// Wait blocks until the provider may receive another request. It returns early with
+// the context's error if the context is cancelled first. A slot is only taken once
+// the caller is let through, so cancelled callers do not use up the provider's
+// budget.
+func Wait(ctx context.Context, providerId string) error {
+	l := getLimiter(providerId)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		l.mu.Lock()
+		now := limiterClock.Now()
+		start := l.next
+		if l.blockedUntil.After(start) {
+			start = l.blockedUntil
+		}
+		if !start.After(now) {
+			l.next = now.Add(l.interval)
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		// Another caller may take the slot first, so check again after waiting.
+		wake, stop := limiterClock.After(start.Sub(now))
+		select {
+		case <-wake:
+			stop()
+		case <-ctx.Done():
+			stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// observeRateLimit reads the rate limit headers of a response. A 429, or a response
+// saying no requests remain, blocks the provider until the time the server gave.
+//
+// The block applies to the whole provider, even when the response came through one
+// proxy. Providers such as MangaDex also limit by client and User-Agent, so moving
+// on to another proxy straight away tends to get that one limited as well.
+func observeRateLimit(providerId string, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	exhausted := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if resp.StatusCode != http.StatusTooManyRequests && !exhausted {
+		return
+	}
+
+	until, ok := retryAfter(resp.Header, limiterClock.Now())
+	if !ok {
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return
+		}
+		// Rate limited without being told for how long.
+		until = limiterClock.Now().Add(defaultRetryAfter)
+	}
+
+	l := getLimiter(providerId)
+
+	l.mu.Lock()
+	if until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+	l.mu.Unlock()
+}
+
+// retryAfter returns when requests may resume according to the Retry-After header
+// (seconds or an HTTP date) or X-RateLimit-Retry-After/X-RateLimit-Reset (a unix
+// timestamp).
+func retryAfter(header http.Header, now time.Time) (time.Time, bool) {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return date, true
+		}
+	}
+
+	for _, name := range []string{"X-RateLimit-Retry-After", "X-RateLimit-Reset"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(timestamp, 0), true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,233 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when Advance is called. Using one resets every limiter.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	// waiting receives every time a caller starts waiting.
+	waiting chan struct{}
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+
+	c := &fakeClock{
+		now:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		waiting: make(chan struct{}, 16),
+	}
+	previous := limiterClock
+	limiterClock = c
+	t.Cleanup(func() { limiterClock = previous })
+
+	// Limiters remember when the next request may go out, which only makes sense
+	// against the clock they were used with.
+	limitersMu.Lock()
+	limiters = make(map[string]*limiter)
+	limitersMu.Unlock()
+
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	c.mu.Unlock()
+
+	c.waiting <- struct{}{}
+	return ch, func() {}
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	var pending []fakeWaiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// waitAsync calls Wait in a goroutine and returns its result channel once the call
+// is blocked on the clock.
+func waitAsync(t *testing.T, c *fakeClock, ctx context.Context, providerId string) <-chan error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- Wait(ctx, providerId) }()
+
+	select {
+	case <-c.waiting:
+	case err := <-done:
+		t.Fatalf("Wait returned %v instead of blocking", err)
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not start waiting")
+	}
+	return done
+}
+
+func expectDone(t *testing.T, done <-chan error, want error) {
+	t.Helper()
+
+	select {
+	case err := <-done:
+		if err != want {
+			t.Fatalf("Wait = %v, want %v", err, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return")
+	}
+}
+
+func TestWaitSpacesRequests(t *testing.T) {
+	c := useFakeClock(t)
+	SetRateLimit("limiter-spacing", time.Second)
+
+	if err := Wait(context.Background(), "limiter-spacing"); err != nil {
+		t.Fatalf("first Wait = %v", err)
+	}
+
+	done := waitAsync(t, c, context.Background(), "limiter-spacing")
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v before the interval passed", err)
+	default:
+	}
+
+	c.Advance(500 * time.Millisecond)
+	expectDone(t, done, nil)
+}
+
+func TestWaitCancelledReleasesSlot(t *testing.T) {
+	c := useFakeClock(t)
+	SetRateLimit("limiter-cancel", time.Second)
+
+	if err := Wait(context.Background(), "limiter-cancel"); err != nil {
+		t.Fatalf("first Wait = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(t, c, ctx, "limiter-cancel")
+	cancel()
+	expectDone(t, done, context.Canceled)
+
+	// The cancelled caller did not take the slot after the first request, so the
+	// next caller gets it as soon as the interval has passed.
+	c.Advance(time.Second)
+	if err := Wait(context.Background(), "limiter-cancel"); err != nil {
+		t.Fatalf("Wait after cancel = %v", err)
+	}
+	select {
+	case <-c.waiting:
+		t.Fatal("Wait after cancel had to wait for the cancelled slot")
+	default:
+	}
+}
+
+func TestWaitCancelledBeforeStart(t *testing.T) {
+	useFakeClock(t)
+	SetRateLimit("limiter-precancel", time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, "limiter-precancel"); err != context.Canceled {
+		t.Fatalf("Wait = %v, want %v", err, context.Canceled)
+	}
+
+	// No slot was taken, so the next request goes straight through.
+	if err := Wait(context.Background(), "limiter-precancel"); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+}
+
+func TestObserveRateLimitBlocksProvider(t *testing.T) {
+	c := useFakeClock(t)
+	SetRateLimit("limiter-429", 0)
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "5")
+	observeRateLimit("limiter-429", resp)
+
+	done := waitAsync(t, c, context.Background(), "limiter-429")
+
+	c.Advance(4 * time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v while blocked", err)
+	default:
+	}
+
+	c.Advance(time.Second)
+	expectDone(t, done, nil)
+}
+
+func TestObserveRateLimitDefault(t *testing.T) {
+	c := useFakeClock(t)
+	SetRateLimit("limiter-default", 0)
+
+	observeRateLimit("limiter-default", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+
+	done := waitAsync(t, c, context.Background(), "limiter-default")
+	c.Advance(defaultRetryAfter)
+	expectDone(t, done, nil)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   time.Time
+		ok     bool
+	}{
+		{"seconds", map[string]string{"Retry-After": "30"}, now.Add(30 * time.Second), true},
+		{"date", map[string]string{"Retry-After": "Mon, 01 Jan 2024 00:01:00 GMT"}, now.Add(time.Minute), true},
+		{"retry after timestamp", map[string]string{"X-RateLimit-Retry-After": "1704067260"}, now.Add(time.Minute), true},
+		{"reset timestamp", map[string]string{"X-RateLimit-Reset": "1704067320"}, now.Add(2 * time.Minute), true},
+		{"none", map[string]string{}, time.Time{}, false},
+		{"invalid", map[string]string{"Retry-After": "soon"}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.header {
+				header.Set(key, value)
+			}
+
+			got, ok := retryAfter(header, now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("retryAfter = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
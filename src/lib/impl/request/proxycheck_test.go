@@ -0,0 +1,87 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	proxies "anify/eltik/go/src/lib/impl/proxies"
+)
+
+// newTestProxy serves requests for any target with the given status, like a CORS
+// proxy that prefixes the target URL.
+func newTestProxy(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/https://") {
+			t.Errorf("proxy got %s, want the target URL as the path", r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCheckProxy(t *testing.T) {
+	good := newTestProxy(t, http.StatusOK)
+	bad := newTestProxy(t, http.StatusServiceUnavailable)
+
+	previous := proxies.MangaProxies
+	proxies.MangaProxies = []proxies.Proxy{{IP: good.URL}, {IP: bad.URL}}
+	t.Cleanup(func() { proxies.MangaProxies = previous })
+
+	working, err := CheckProxy("mangadex", "https://api.mangadex.org/ping")
+	if err != nil || !working {
+		t.Fatalf("CheckProxy = %v, %v, want true, nil", working, err)
+	}
+
+	results := make(map[string]proxies.Health)
+	for _, h := range proxies.GetHealth("mangadex") {
+		results[h.IP] = h
+	}
+	if h := results[good.URL]; h.Successes != 1 || h.LastStatus != http.StatusOK {
+		t.Errorf("good proxy health = %+v", h)
+	}
+	if h := results[bad.URL]; h.ConsecutiveFailures != 1 || h.LastStatus != http.StatusServiceUnavailable {
+		t.Errorf("bad proxy health = %+v", h)
+	}
+}
+
+func TestCheckProxyWithoutProxies(t *testing.T) {
+	previous := proxies.MetaProxies
+	proxies.MetaProxies = nil
+	t.Cleanup(func() { proxies.MetaProxies = previous })
+
+	if _, err := CheckProxy("no-proxies", "https://example.com"); !errors.Is(err, ErrNoProxies) {
+		t.Fatalf("CheckProxy error = %v, want ErrNoProxies", err)
+	}
+}
+
+func TestRequestErrorPermanent(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt Attempt
+		want    bool
+	}{
+		{"direct not found", Attempt{Status: http.StatusNotFound}, true},
+		{"direct forbidden", Attempt{Status: http.StatusForbidden}, true},
+		{"proxy not found", Attempt{Proxy: "http://proxy", Status: http.StatusNotFound}, true},
+		{"proxy forbidden", Attempt{Proxy: "http://proxy", Status: http.StatusForbidden}, false},
+		{"proxy rate limited", Attempt{Proxy: "http://proxy", Status: http.StatusTooManyRequests}, false},
+		{"proxy server error", Attempt{Proxy: "http://proxy", Status: http.StatusBadGateway}, false},
+		{"connection error", Attempt{Err: errors.New("refused")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &RequestError{Attempts: []Attempt{tt.attempt}}
+			if got := err.Permanent(); got != tt.want {
+				t.Errorf("Permanent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,40 +1,65 @@
 package request
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	proxies "anify/eltik/go/src/lib/impl/proxies"
 )
 
+// GetRandomUnbannedProxy picks a random proxy for the provider out of the ones that
+// are not currently banned. It returns nil when none are left.
 func GetRandomUnbannedProxy(providerId string) *string {
-	var data []proxies.Proxy
+	return pickProxy(providerId, nil)
+}
 
-	switch {
-	case providerId == "novelupdates":
-		data = proxies.MangaProxies
-	case contains(providerId, []string{"base1"}):
-		data = proxies.BaseProxies
-	case contains(providerId, []string{"anime1"}):
-		data = proxies.AnimeProxies
-	case contains(providerId, []string{"manga1", "mangadex"}):
-		data = proxies.MangaProxies
-	default:
-		data = proxies.MetaProxies
-	}
+// pickProxy picks a random unbanned proxy, preferring ones not in tried. It only
+// falls back to an already tried proxy when every unbanned one has been used.
+func pickProxy(providerId string, tried map[string]bool) *string {
+	data := proxies.Unbanned(providerId, proxiesFor(providerId))
 
 	if len(data) == 0 {
 		return nil
 	}
 
+	var untried []proxies.Proxy
+	for _, proxy := range data {
+		if !tried[proxy.IP] {
+			untried = append(untried, proxy)
+		}
+	}
+	if len(untried) > 0 {
+		data = untried
+	}
+
 	randomProxy := data[rand.Intn(len(data))]
 	return &randomProxy.IP
 }
 
+// proxiesFor returns the proxy list a provider's requests go through.
+func proxiesFor(providerId string) []proxies.Proxy {
+	switch {
+	case providerId == "novelupdates":
+		return proxies.MangaProxies
+	case contains(providerId, []string{"base1"}):
+		return proxies.BaseProxies
+	case contains(providerId, []string{"anime1"}):
+		return proxies.AnimeProxies
+	case contains(providerId, []string{"manga1", "mangadex"}):
+		return proxies.MangaProxies
+	default:
+		return proxies.MetaProxies
+	}
+}
+
 // contains checks if a provider ID exists in a list
 func contains(s string, list []string) bool {
 	for _, v := range list {
@@ -45,76 +70,255 @@ func contains(s string, list []string) bool {
 	return false
 }
 
-func Request(providerId string, useGoogleTranslate bool, config http.Request, proxyRequest bool) (*http.Response, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// Options describes a provider request.
+type Options struct {
+	// Context cancels the request, including rate limit and retry waits. It defaults
+	// to context.Background().
+	Context context.Context
+	// Method defaults to GET.
+	Method string
+	URL    string
+	// Headers are sent as-is in every branch. A User-Agent is added when missing.
+	Headers http.Header
+	Body    []byte
+}
+
+// context returns the request context, falling back to context.Background().
+func (o Options) context() context.Context {
+	if o.Context != nil {
+		return o.Context
 	}
+	return context.Background()
+}
 
-	copyHeaders := func(src, dst http.Header) {
-		for key, values := range src {
-			for _, value := range values {
-				dst.Add(key, value)
-			}
-		}
+// Request sends the request for the provider, either directly, through Google
+// Translate or through a random unbanned proxy. Connection errors and retryable
+// statuses are retried with backoff according to the provider's RetryPolicy,
+// switching to a different proxy each time. When no attempt succeeds, or the
+// upstream answers with a permanent error such as 404, a *RequestError listing
+// every attempt is returned.
+func Request(providerId string, useGoogleTranslate bool, opts Options, proxyRequest bool) (*http.Response, error) {
+	if opts.Method == "" {
+		opts.Method = http.MethodGet
 	}
 
-	if proxyRequest {
-		if useGoogleTranslate {
-			encodedURL := url.QueryEscape(config.URL.String())
+	if _, err := url.Parse(opts.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
 
-			// Modify the request to use Google Translate.
-			translatedURL := fmt.Sprintf("http://translate.google.com/translate?sl=ja&tl=en&u=%s", encodedURL)
-			req, err := http.NewRequestWithContext(context.Background(), config.Method, translatedURL, config.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %w", err)
-			}
+	ctx := opts.context()
+	policy := GetRetryPolicy(providerId)
+	httpClient := Client()
+
+	// Recorded requests always go direct so they replay the same way.
+	cassette := currentCassette()
+	if cassette != nil {
+		proxyRequest = false
+	}
+	replaying := cassette != nil && cassette.Mode == CassetteReplay
 
-			// Copy headers from the original request.
-			req.Header = config.Header
+	reqErr := &RequestError{ProviderID: providerId, URL: opts.URL}
+	tried := make(map[string]bool)
 
-			// Send the request via Google Translate.
-			resp, err := client.Do(req)
-			if err != nil {
-				return nil, fmt.Errorf("request through Google Translate failed: %w", err)
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := sleep(ctx, policy.Backoff(attempt-1)); err != nil {
+				reqErr.Attempts = append(reqErr.Attempts, Attempt{Err: err})
+				return nil, reqErr
 			}
-			return resp, nil
 		}
 
-		proxy := GetRandomUnbannedProxy(providerId)
-		if proxy == nil {
-			return nil, fmt.Errorf("no unbanned proxy available for provider: %s", providerId)
+		// Respect the provider's rate limit before sending anything.
+		if !replaying {
+			if err := Wait(ctx, providerId); err != nil {
+				reqErr.Attempts = append(reqErr.Attempts, Attempt{Err: err})
+				return nil, reqErr
+			}
 		}
 
-		proxiedURL := fmt.Sprintf("%s/%s", *proxy, config.URL.String())
-		req, err := http.NewRequestWithContext(context.Background(), config.Method, proxiedURL, config.Body)
+		req, proxy, err := newAttemptRequest(ctx, providerId, useGoogleTranslate, opts, proxyRequest, tried)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+			reqErr.Attempts = append(reqErr.Attempts, Attempt{Err: err})
+			return nil, reqErr
+		}
+		if proxy != "" {
+			tried[proxy] = true
 		}
 
-		req.Header.Set("Origin", config.URL.String())
-		copyHeaders(config.Header, req.Header)
+		start := time.Now()
+		resp, err := httpClient.Do(req)
+		latency := time.Since(start)
 
-		// Send the request via the unbanned proxy.
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("request through proxy failed: %w", err)
+		observeRateLimit(providerId, resp)
+		if proxy != "" {
+			reportProxy(providerId, proxy, latency, resp, err)
 		}
 
-		return resp, nil
-	} else {
-		req, err := http.NewRequestWithContext(context.Background(), config.Method, config.URL.String(), config.Body)
+		current := Attempt{Proxy: proxy, Duration: latency, Err: err}
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+			reqErr.Attempts = append(reqErr.Attempts, current)
+			if ctx.Err() != nil || errors.Is(err, ErrNoInteraction) {
+				return nil, reqErr
+			}
+			continue
 		}
 
-		// Send the request directly.
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("request failed: %w", err)
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		current.Status = resp.StatusCode
+		reqErr.Attempts = append(reqErr.Attempts, current)
+
+		// A proxy being blocked is worth retrying elsewhere, even if the same status
+		// from the upstream itself would be final.
+		if !IsRetryableStatus(resp.StatusCode) && !(proxy != "" && proxies.IsFailureStatus(resp.StatusCode)) {
+			return nil, reqErr
+		}
+	}
+
+	return nil, reqErr
+}
+
+// newAttemptRequest builds the request for one attempt and returns the proxy it
+// goes through, if any. Every branch sends the caller's headers and context.
+func newAttemptRequest(ctx context.Context, providerId string, useGoogleTranslate bool, opts Options, proxyRequest bool, tried map[string]bool) (*http.Request, string, error) {
+	target := opts.URL
+	proxy := ""
+
+	if proxyRequest {
+		if useGoogleTranslate {
+			// Send the request through Google Translate.
+			target = fmt.Sprintf("http://translate.google.com/translate?sl=ja&tl=en&u=%s", url.QueryEscape(opts.URL))
+		} else {
+			picked := pickProxy(providerId, tried)
+			if picked == nil {
+				return nil, "", fmt.Errorf("no unbanned proxy available for provider: %s", providerId)
+			}
+			proxy = *picked
+			target = fmt.Sprintf("%s/%s", proxy, opts.URL)
 		}
+	}
+
+	var body io.Reader
+	if opts.Body != nil {
+		body = bytes.NewReader(opts.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, opts.Method, target, body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, values := range opts.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", userAgent(providerId))
+	}
+	if proxy != "" && req.Header.Get("Origin") == "" {
+		req.Header.Set("Origin", opts.URL)
+	}
+
+	return req, proxy, nil
+}
+
+// sleep waits for d or until the context is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reportProxy records the outcome of a proxied request in the proxy health table.
+func reportProxy(providerId string, proxy string, latency time.Duration, resp *http.Response, err error) {
+	if err != nil {
+		proxies.ReportFailure(providerId, proxy, latency, 0, err)
+		return
+	}
+
+	if proxies.IsFailureStatus(resp.StatusCode) {
+		proxies.ReportFailure(providerId, proxy, latency, resp.StatusCode, nil)
+		return
+	}
+
+	proxies.ReportSuccess(providerId, proxy, latency, resp.StatusCode)
+}
+
+// proxyCheckConcurrency is how many proxies CheckProxy probes at once.
+const proxyCheckConcurrency = 8
+
+// ErrNoProxies is returned by CheckProxy when no proxies are loaded for the provider.
+var ErrNoProxies = errors.New("no proxies loaded")
+
+// CheckProxy probes every proxy loaded for the provider, banned ones included, with
+// a GET for target and records each outcome in the proxy health table. It reports
+// whether at least one proxy got a usable response.
+func CheckProxy(providerId string, target string) (bool, error) {
+	list := proxiesFor(providerId)
+	if len(list) == 0 {
+		return false, fmt.Errorf("%w for provider: %s", ErrNoProxies, providerId)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		working bool
+	)
+	slots := make(chan struct{}, proxyCheckConcurrency)
+
+	for _, proxy := range list {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(ip string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if ProbeProxy(context.Background(), providerId, ip, target) == nil {
+				mu.Lock()
+				working = true
+				mu.Unlock()
+			}
+		}(proxy.IP)
+	}
+	wg.Wait()
+
+	return working, nil
+}
+
+// ProbeProxy sends a single GET for target through one specific proxy, without
+// retries or rate limiting, and records the outcome in the proxy health table. It
+// returns an error when the request failed or got an error status.
+func ProbeProxy(ctx context.Context, providerId string, proxy string, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", proxy, target), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent(providerId))
+	req.Header.Set("Origin", target)
+
+	start := time.Now()
+	resp, err := Client().Do(req)
+	reportProxy(providerId, proxy, time.Since(start), resp, err)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
 
-		return resp, nil
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
+	return nil
 }
 
 // Response example
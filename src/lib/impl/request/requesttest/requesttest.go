@@ -0,0 +1,29 @@
+// Package requesttest replays cassettes in provider and mapping tests.
+package requesttest
+
+import (
+	"anify/eltik/go/src/lib/impl/request"
+	"path/filepath"
+	"testing"
+)
+
+// OnePiece is the MangaDex ID of One Piece, which the test cassettes are written
+// around.
+const OnePiece = "a1c7c817-4e59-43b7-9365-09675a149a6f"
+
+// UseCassette replays testdata/cassettes/<name> of the calling package for the rest
+// of the test, in the mode given by CASSETTE_MODE. The cassettes are hand-written
+// fixtures shaped like MangaDex responses, not recordings of the live API.
+func UseCassette(t testing.TB, name string) {
+	t.Helper()
+
+	stop, err := request.UseCassette(filepath.Join("testdata", "cassettes", name), request.CassetteModeFromEnv())
+	if err != nil {
+		t.Fatalf("UseCassette: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := stop(); err != nil {
+			t.Errorf("saving cassette: %v", err)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package request
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	proxies "anify/eltik/go/src/lib/impl/proxies"
+)
+
+// RetryPolicy controls how often a failed request is attempted and how long to wait
+// in between.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles for every retry after.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between two attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used for providers without their own policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+var (
+	retryPoliciesMu sync.Mutex
+	retryPolicies   = make(map[string]RetryPolicy)
+)
+
+// SetRetryPolicy overrides the retry policy of a provider.
+func SetRetryPolicy(providerId string, policy RetryPolicy) {
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+
+	retryPolicies[providerId] = policy
+}
+
+// GetRetryPolicy returns the retry policy of a provider, falling back to
+// DefaultRetryPolicy.
+func GetRetryPolicy(providerId string) RetryPolicy {
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+
+	policy, ok := retryPolicies[providerId]
+	if !ok {
+		policy = DefaultRetryPolicy
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return policy
+}
+
+// Backoff returns how long to wait before the given retry (starting at 1). The delay
+// grows exponentially and is jittered between half and the full value so that
+// concurrent callers do not retry in lockstep.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// IsRetryableStatus reports whether a response status is temporary: a timeout, a
+// rate limit or a server error. Any other 4xx is permanent.
+func IsRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= 500
+}
+
+// Attempt is the outcome of a single try of a request.
+type Attempt struct {
+	// Proxy is the proxy used, or empty for direct and Google Translate requests.
+	Proxy    string
+	Status   int
+	Err      error
+	Duration time.Duration
+}
+
+// RequestError is returned by Request when it gives up. It lists every attempt so
+// callers can tell a permanent upstream answer from every proxy failing.
+type RequestError struct {
+	ProviderID string
+	URL        string
+	Attempts   []Attempt
+}
+
+func (e *RequestError) Error() string {
+	var parts []string
+	for i, attempt := range e.Attempts {
+		part := fmt.Sprintf("#%d", i+1)
+		if attempt.Proxy != "" {
+			part += " via " + attempt.Proxy
+		}
+		if attempt.Err != nil {
+			part += ": " + attempt.Err.Error()
+		} else {
+			part += fmt.Sprintf(": status %d", attempt.Status)
+		}
+		parts = append(parts, part)
+	}
+
+	return fmt.Sprintf("request to %s for %s failed after %d attempt(s) (%s)", e.URL, e.ProviderID, len(e.Attempts), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the error of the last attempt, if it had one.
+func (e *RequestError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// StatusCode returns the status of the last attempt that got a response, or 0.
+func (e *RequestError) StatusCode() int {
+	for i := len(e.Attempts) - 1; i >= 0; i-- {
+		if e.Attempts[i].Status != 0 {
+			return e.Attempts[i].Status
+		}
+	}
+	return 0
+}
+
+// Permanent reports whether the request was given up because the upstream answered
+// with a status that retrying will not change, such as 404.
+func (e *RequestError) Permanent() bool {
+	if len(e.Attempts) == 0 {
+		return false
+	}
+
+	last := e.Attempts[len(e.Attempts)-1]
+	return last.Err == nil && last.Status >= 400 && !IsRetryableStatus(last.Status) && !(last.Proxy != "" && proxies.IsFailureStatus(last.Status))
+}
+
+// AllProxiesFailed reports whether every attempt went through a proxy and none of
+// them got a usable answer.
+func (e *RequestError) AllProxiesFailed() bool {
+	if len(e.Attempts) == 0 || e.Permanent() {
+		return false
+	}
+
+	for _, attempt := range e.Attempts {
+		if attempt.Proxy == "" {
+			return false
+		}
+	}
+	return true
+}
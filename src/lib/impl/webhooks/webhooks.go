@@ -0,0 +1,274 @@
+package webhooks
+
+import (
+	"anify/eltik/go/src/database"
+	events "anify/eltik/go/src/lib"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the body,
+	// keyed with the webhook's secret.
+	SignatureHeader = "X-Anify-Signature"
+	EventHeader     = "X-Anify-Event"
+	DeliveryHeader  = "X-Anify-Delivery"
+
+	// TestTopic is the event sent by Dispatcher.Test.
+	TestTopic = "webhook.test"
+)
+
+// Config controls how events are delivered.
+type Config struct {
+	// Workers is how many deliveries run at the same time.
+	Workers int
+	// Buffer is how many events may wait for a worker. Events beyond it are dropped
+	// so publishers are never blocked.
+	Buffer int
+	// MaxAttempts is how often a delivery is tried before giving up.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles for every retry up to
+	// MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout limits a single attempt.
+	Timeout time.Duration
+}
+
+var DefaultConfig = Config{
+	Workers:     4,
+	Buffer:      256,
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Timeout:     10 * time.Second,
+}
+
+// Dispatcher sends bus events to the registered webhooks.
+type Dispatcher struct {
+	repo   database.Repository
+	config Config
+	client *http.Client
+}
+
+func New(repo database.Repository, config Config) *Dispatcher {
+	if config.Workers < 1 {
+		config.Workers = DefaultConfig.Workers
+	}
+	if config.Buffer < 1 {
+		config.Buffer = DefaultConfig.Buffer
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig.Timeout
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Run subscribes to every bus event and delivers them until the context is
+// cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	queue := make(chan events.Envelope, d.config.Buffer)
+
+	unsubscribe := events.SubscribeAll(func(event events.Event) {
+		select {
+		case queue <- events.NewEnvelope(event):
+		default:
+			log.Printf("Webhook queue is full, dropping %s event", event.Topic())
+		}
+	})
+	defer unsubscribe()
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case envelope := <-queue:
+					d.dispatch(ctx, envelope)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// dispatch delivers an envelope to every active webhook subscribed to its event.
+func (d *Dispatcher) dispatch(ctx context.Context, envelope events.Envelope) {
+	hooks, err := d.repo.ListWebhooks(ctx, envelope.Event)
+	if err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", envelope.Event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if err := d.Deliver(ctx, hook, envelope); err != nil {
+			log.Printf("Failed to deliver %s to webhook %d: %v", envelope.Event, hook.ID, err)
+		}
+	}
+}
+
+// Deliver sends an envelope to a webhook, retrying with backoff until it succeeds
+// or MaxAttempts is reached. Every attempt is written to the delivery log.
+func (d *Dispatcher) Deliver(ctx context.Context, hook database.Webhook, envelope events.Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			if !sleep(ctx, d.backoff(attempt-1)) {
+				return ctx.Err()
+			}
+		}
+
+		lastErr = d.attempt(ctx, hook, envelope, body, attempt)
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	return lastErr
+}
+
+// Test sends a single test event to the webhook without retrying and returns the
+// logged attempt.
+func (d *Dispatcher) Test(ctx context.Context, hook database.Webhook) (database.WebhookDelivery, error) {
+	envelope := events.NewEnvelope(testEvent{Message: "This is a test delivery from Anify."})
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+
+	delivery, err := d.send(ctx, hook, envelope, body, 1)
+	if logErr := d.repo.LogWebhookDelivery(ctx, delivery); logErr != nil {
+		log.Printf("Failed to log webhook delivery: %v", logErr)
+	}
+
+	return delivery, err
+}
+
+// attempt makes one delivery attempt and logs it.
+func (d *Dispatcher) attempt(ctx context.Context, hook database.Webhook, envelope events.Envelope, body []byte, attempt int) error {
+	delivery, err := d.send(ctx, hook, envelope, body, attempt)
+
+	// The log is written even when the context was cancelled mid-request.
+	logCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if logErr := d.repo.LogWebhookDelivery(logCtx, delivery); logErr != nil {
+		log.Printf("Failed to log webhook delivery: %v", logErr)
+	}
+
+	return err
+}
+
+// send POSTs the signed body to the webhook.
+func (d *Dispatcher) send(ctx context.Context, hook database.Webhook, envelope events.Envelope, body []byte, attempt int) (database.WebhookDelivery, error) {
+	delivery := database.WebhookDelivery{
+		WebhookID:  hook.ID,
+		DeliveryID: envelope.ID,
+		Event:      envelope.Event,
+		Payload:    body,
+		Attempt:    attempt,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return failed(delivery, err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Anify-Webhooks")
+	req.Header.Set(EventHeader, envelope.Event)
+	req.Header.Set(DeliveryHeader, envelope.ID)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	delivery.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		return failed(delivery, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	status := resp.StatusCode
+	delivery.Status = &status
+
+	if status < 200 || status >= 300 {
+		return failed(delivery, fmt.Errorf("unexpected status code: %d", status))
+	}
+
+	delivery.Success = true
+	return delivery, nil
+}
+
+// Sign returns the signature header value for a body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the given retry (starting at 1).
+func (d *Dispatcher) backoff(retry int) time.Duration {
+	delay := d.config.BaseDelay
+	for i := 1; i < retry && delay < d.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if d.config.MaxDelay > 0 && delay > d.config.MaxDelay {
+		delay = d.config.MaxDelay
+	}
+	return delay
+}
+
+func failed(delivery database.WebhookDelivery, err error) (database.WebhookDelivery, error) {
+	message := err.Error()
+	delivery.Error = &message
+	return delivery, err
+}
+
+// sleep waits for d and reports whether the context is still active.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// testEvent is the payload sent by Dispatcher.Test.
+type testEvent struct {
+	Message string `json:"message"`
+}
+
+func (testEvent) Topic() string { return TestTopic }
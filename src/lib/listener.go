@@ -5,19 +5,36 @@ import (
 )
 
 func Listen() {
-	Bus.Subscribe(COMPLETED_MAPPING_LOAD, func() {
-		fmt.Println("Entry creation completed!")
+	Subscribe(func(e MappingLoadCompleted) {
+		switch {
+		case e.Existing:
+			fmt.Printf("Mapping load completed for %s %s: already stored.\n", e.Type, e.MediaID)
+		default:
+			fmt.Printf("Mapping load completed for %s %s: %d mapping(s) in %dms.\n", e.Type, e.MediaID, len(e.Mappings), e.DurationMs)
+		}
 	})
 
-	Bus.Subscribe(COMPLETED_ENTRY_CREATION, func() {
-		fmt.Println("Entry creation completed!")
+	Subscribe(func(e MappingLoadFailed) {
+		fmt.Printf("Mapping load failed for %s %s after %dms: %s\n", e.Type, e.MediaID, e.DurationMs, e.Error)
 	})
 
-	Bus.Subscribe(COMPLETED_SEARCH_LOAD, func() {
-		fmt.Println("Search load completed!")
+	Subscribe(func(e EntryCreated) {
+		fmt.Printf("Entry creation completed for %s %s (%s).\n", e.Type, e.MediaID, e.Slug)
 	})
 
-	Bus.Subscribe(COMPLETED_SEASONAL_LOAD, func() {
-		fmt.Println("Seasonal load completed!")
+	Subscribe(func(e SearchLoadCompleted) {
+		fmt.Printf("Search load completed for %q on %s: %d result(s) in %dms.\n", e.Query, e.ProviderID, e.Results, e.DurationMs)
+	})
+
+	Subscribe(func(e SeasonalLoadCompleted) {
+		fmt.Printf("Seasonal load completed on %s in %dms.\n", e.ProviderID, e.DurationMs)
+	})
+
+	Subscribe(func(e ChaptersUpdated) {
+		fmt.Printf("%d new chapter(s) for %s on %s, latest %g.\n", e.New, e.MediaID, e.ProviderID, e.Latest)
+	})
+
+	Subscribe(func(e ProxyBanned) {
+		fmt.Printf("Proxy %s banned for %s until %s.\n", e.Proxy, e.ProviderID, e.Until.Format("15:04:05"))
 	})
 }
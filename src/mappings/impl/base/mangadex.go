@@ -2,12 +2,13 @@ package base
 
 import (
 	"anify/eltik/go/src/lib/impl/helper"
+	"anify/eltik/go/src/lib/impl/request"
 	"anify/eltik/go/src/types"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,10 +19,14 @@ type MangaDexBaseProvider struct {
 	Api string
 }
 
+// now is replaced in tests so the seasonal URLs do not change from day to day.
+var now = time.Now
+
 func NewMangaDexBaseProvider() *MangaDexBaseProvider {
 	return &MangaDexBaseProvider{
 		BaseBaseProvider: types.BaseBaseProvider{
 			RateLimit:          250,
+			UserAgent:          "Anify (https://github.com/Eltik/Anify-Go)",
 			Id:                 "mangadex",
 			Url:                "https://mangadex.org",
 			Formats:            []types.Format{types.FormatManga, types.FormatOneShot},
@@ -49,8 +54,8 @@ func (p *MangaDexBaseProvider) Search(query string, mediaType types.Type, format
 		q.Add("includes[]", "cover_art")
 		uri.RawQuery = q.Encode()
 
-		resp, err := p.Request(http.Request{
-			URL:    uri,
+		resp, err := p.Request(request.Options{
+			URL:    uri.String(),
 			Method: "GET",
 		}, &p.NeedsProxy)
 		if err != nil {
@@ -130,8 +135,8 @@ func (p *MangaDexBaseProvider) SearchAdvanced(query string, mediaType types.Type
 	if len(tags) > 0 || len(tagsExcluded) > 0 {
 		uri, _ := url.Parse(p.Api + "/manga/tag")
 
-		resp, err := p.Request(http.Request{
-			URL:    uri,
+		resp, err := p.Request(request.Options{
+			URL:    uri.String(),
 			Method: "GET",
 		}, &p.NeedsProxy)
 
@@ -264,8 +269,8 @@ func (p *MangaDexBaseProvider) SearchAdvanced(query string, mediaType types.Type
 
 		uri.RawQuery = q.Encode()
 
-		resp, err := p.Request(http.Request{
-			URL:    uri,
+		resp, err := p.Request(request.Options{
+			URL:    uri.String(),
 			Method: "GET",
 		}, &p.NeedsProxy)
 		if err != nil {
@@ -343,8 +348,8 @@ func (p *MangaDexBaseProvider) GetCurrentSeason() (types.Season, error) {
 func (p *MangaDexBaseProvider) GetMedia(id string) (types.MediaInfo, error) {
 	uri, _ := url.Parse(p.Api + "/manga/" + id)
 
-	data, err := p.Request(http.Request{
-		URL:    uri,
+	data, err := p.Request(request.Options{
+		URL:    uri.String(),
 		Method: "GET",
 	}, &p.NeedsProxy)
 	if err != nil {
@@ -415,7 +420,7 @@ func (p *MangaDexBaseProvider) GetMedia(id string) (types.MediaInfo, error) {
 }
 
 func (p *MangaDexBaseProvider) GetSeasonal(mediaType types.Type, formats []types.Format) (types.SeasonalResponse, error) {
-	currentDate := time.Now().AddDate(0, 0, -3)
+	currentDate := now().AddDate(0, 0, -3)
 	createdAtParam := fmt.Sprintf("%04d-%02d-%02dT00:00:00",
 		currentDate.Year(),
 		int(currentDate.Month()),
@@ -428,8 +433,8 @@ func (p *MangaDexBaseProvider) GetSeasonal(mediaType types.Type, formats []types
 	// https://mangadex.org/titles/seasonal
 	seasonalUri, _ := url.Parse(p.Api + "/list/54736a5c-eb7f-4844-971b-80ee171cdf29?includes[]=user")
 
-	trending, err := p.Request(http.Request{
-		URL:    trendingUri,
+	trending, err := p.Request(request.Options{
+		URL:    trendingUri.String(),
 		Method: "GET",
 	}, &p.NeedsProxy)
 	if err != nil {
@@ -456,8 +461,8 @@ func (p *MangaDexBaseProvider) GetSeasonal(mediaType types.Type, formats []types
 		return types.SeasonalResponse{}, fmt.Errorf("error parsing JSON: %w", err)
 	}
 
-	popular, err := p.Request(http.Request{
-		URL:    popularUri,
+	popular, err := p.Request(request.Options{
+		URL:    popularUri.String(),
 		Method: "GET",
 	}, &p.NeedsProxy)
 	if err != nil {
@@ -484,8 +489,8 @@ func (p *MangaDexBaseProvider) GetSeasonal(mediaType types.Type, formats []types
 		return types.SeasonalResponse{}, fmt.Errorf("error parsing JSON: %w", err)
 	}
 
-	top, err := p.Request(http.Request{
-		URL:    topUri,
+	top, err := p.Request(request.Options{
+		URL:    topUri.String(),
 		Method: "GET",
 	}, &p.NeedsProxy)
 	if err != nil {
@@ -512,8 +517,8 @@ func (p *MangaDexBaseProvider) GetSeasonal(mediaType types.Type, formats []types
 		return types.SeasonalResponse{}, fmt.Errorf("error parsing JSON: %w", err)
 	}
 
-	seasonal, err := p.Request(http.Request{
-		URL:    seasonalUri,
+	seasonal, err := p.Request(request.Options{
+		URL:    seasonalUri.String(),
 		Method: "GET",
 	}, &p.NeedsProxy)
 	if err != nil {
@@ -558,8 +563,8 @@ func (p *MangaDexBaseProvider) GetIds() ([]string, error) {
 		return nil, fmt.Errorf("error parsing URL: %w", err)
 	}
 
-	ids, err := p.Request(http.Request{
-		URL:    uri,
+	ids, err := p.Request(request.Options{
+		URL:    uri.String(),
 		Method: "GET",
 	}, &p.NeedsProxy)
 	if err != nil {
@@ -582,14 +587,26 @@ func (p *MangaDexBaseProvider) GetIds() ([]string, error) {
 		return nil, fmt.Errorf("error parsing JSON: %w", err)
 	}
 
-	var idList []string
-	for id := range idsData {
+	// The map goes from legacy numeric IDs to the current UUIDs, and several legacy
+	// IDs can point at the same manga.
+	seen := make(map[string]bool, len(idsData))
+	idList := make([]string, 0, len(idsData))
+	for _, id := range idsData {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
 		idList = append(idList, id)
 	}
+	sort.Strings(idList)
 
 	return idList, nil
 }
 
+func (p *MangaDexBaseProvider) ProxyCheck() (bool, error) {
+	return request.CheckProxy(p.Id, p.Api+"/ping")
+}
+
 func returnFilledManga(p *MangaDexBaseProvider, seasonalData MangaDexSeasonal) []types.MediaInfo {
 	var results []types.MediaInfo
 	for _, data := range seasonalData.Data {
@@ -0,0 +1,99 @@
+package base
+
+import (
+	"anify/eltik/go/src/lib/impl/request/requesttest"
+	"anify/eltik/go/src/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetMedia(t *testing.T) {
+	requesttest.UseCassette(t, "mangadex_media.json")
+
+	media, err := NewMangaDexBaseProvider().GetMedia(requesttest.OnePiece)
+	if err != nil {
+		t.Fatalf("GetMedia: %v", err)
+	}
+
+	if media.ID != requesttest.OnePiece || media.Type != types.TypeManga || media.Format != types.FormatManga {
+		t.Errorf("GetMedia = %s %s %s", media.ID, media.Type, media.Format)
+	}
+	if media.Title.English == nil || *media.Title.English != "One Piece" {
+		t.Errorf("English title = %v, want One Piece", media.Title.English)
+	}
+	if media.Title.Romaji == nil || media.Title.Native == nil {
+		t.Errorf("romaji or native title missing: %+v", media.Title)
+	}
+	if media.Year == nil || *media.Year != 1997 {
+		t.Errorf("year = %v, want 1997", media.Year)
+	}
+	if media.Author == nil || *media.Author == "" {
+		t.Error("author missing")
+	}
+	if media.CoverImage == nil || !strings.Contains(*media.CoverImage, requesttest.OnePiece) {
+		t.Errorf("cover image = %v", media.CoverImage)
+	}
+	if !contains(media.Genres, "Action") {
+		t.Errorf("genres = %v, want Action", media.Genres)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	requesttest.UseCassette(t, "mangadex_search.json")
+
+	results, err := NewMangaDexBaseProvider().Search("One Piece", types.TypeManga, []types.Format{types.FormatManga}, 1, 25)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("Search returned no results")
+	}
+
+	first := results[0]
+	if first.ID != requesttest.OnePiece || first.Title.English == nil || *first.Title.English != "One Piece" {
+		t.Errorf("first result = %s %v, want One Piece", first.ID, first.Title.English)
+	}
+	for _, result := range results {
+		if result.ID == "" || result.Type != types.TypeManga {
+			t.Errorf("result = %s %s", result.ID, result.Type)
+		}
+	}
+}
+
+func TestGetSeasonal(t *testing.T) {
+	previous := now
+	now = func() time.Time { return time.Date(2024, 10, 25, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = previous })
+
+	requesttest.UseCassette(t, "mangadex_seasonal.json")
+
+	seasonal, err := NewMangaDexBaseProvider().GetSeasonal(types.TypeManga, []types.Format{types.FormatManga})
+	if err != nil {
+		t.Fatalf("GetSeasonal: %v", err)
+	}
+
+	for name, list := range map[string][]types.MediaInfo{
+		"trending": seasonal.Trending,
+		"popular":  seasonal.Popular,
+		"top":      seasonal.Top,
+	} {
+		if len(list) == 0 {
+			t.Errorf("%s is empty", name)
+		}
+		for _, media := range list {
+			if media.ID == "" || media.Type != types.TypeManga {
+				t.Errorf("%s result = %s %s", name, media.ID, media.Type)
+			}
+		}
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,29 +3,161 @@ package information
 import (
 	"anify/eltik/go/src/lib/impl/request"
 	"anify/eltik/go/src/types"
-	"net/http"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
 )
 
+// coverLimit is the page size used for the cover list, the most the API allows.
+const coverLimit = 100
+
 type MangaDexInformationProvider struct {
 	types.BaseInformationProvider
-	Api string
+	Api     string
+	Uploads string
 }
 
 func NewMangaDexInformationProvider() *MangaDexInformationProvider {
 	return &MangaDexInformationProvider{
 		BaseInformationProvider: types.BaseInformationProvider{
+			RateLimit:          250,
+			UserAgent:          "Anify (https://github.com/Eltik/Anify-Go)",
 			Id:                 "mangadex",
 			Url:                "https://mangadex.org",
 			ProviderType:       types.ProviderTypeManga,
 			NeedsProxy:         true,
 			UseGoogleTranslate: false,
 		},
-		Api: "https://api.mangadex.org",
+		Api:     "https://api.mangadex.org",
+		Uploads: "https://uploads.mangadex.org",
 	}
 }
 
+// Info fetches the synonyms, genres, tags, every volume cover, the rating and the
+// follow count for the manga's MangaDex mapping. Media without a MangaDex mapping
+// get an empty result.
 func (p *MangaDexInformationProvider) Info(media types.Media) (types.MediaInfo, error) {
-	return types.MediaInfo{}, nil
+	id := ""
+	for _, mapping := range media.Mappings {
+		if mapping.ProviderID == p.Id {
+			id = mapping.ID
+			break
+		}
+	}
+	if id == "" {
+		return types.MediaInfo{}, nil
+	}
+
+	var manga MangaDexManga
+	if err := p.get(p.Api+"/manga/"+id, &manga); err != nil {
+		return types.MediaInfo{}, err
+	}
+
+	artwork, err := p.fetchCovers(id)
+	if err != nil {
+		return types.MediaInfo{}, err
+	}
+
+	var statistics MangaDexStatistics
+	if err := p.get(p.Api+"/statistics/manga/"+id, &statistics); err != nil {
+		return types.MediaInfo{}, err
+	}
+
+	info := types.MediaInfo{
+		ID:       id,
+		Type:     types.TypeManga,
+		Artwork:  artwork,
+		Synonyms: extractSynonyms(manga.Data.Attributes),
+		Genres:   extractTagNames(manga.Data.Attributes.Tags, "genre"),
+		Tags:     extractTagNames(manga.Data.Attributes.Tags, "theme"),
+	}
+
+	if stats, ok := statistics.Statistics[id]; ok {
+		// The bayesian rating accounts for how many votes there are, so it is only
+		// replaced by the plain average when MangaDex has not computed it yet.
+		rating := stats.Rating.Bayesian
+		if rating == 0 && stats.Rating.Average != nil {
+			rating = *stats.Rating.Average
+		}
+		if rating > 0 {
+			info.Rating = &rating
+		}
+
+		follows := float64(stats.Follows)
+		info.Popularity = &follows
+	}
+
+	return info, nil
+}
+
+// fetchCovers pages through the cover list and returns every cover as poster
+// artwork, ordered by volume.
+func (p *MangaDexInformationProvider) fetchCovers(id string) ([]types.Artwork, error) {
+	var artwork []types.Artwork
+
+	for offset := 0; ; offset += coverLimit {
+		uri, _ := url.Parse(p.Api + "/cover")
+		q := uri.Query()
+
+		q.Add("manga[]", id)
+		q.Set("limit", strconv.Itoa(coverLimit))
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("order[volume]", "asc")
+		uri.RawQuery = q.Encode()
+
+		var covers MangaDexCovers
+		if err := p.get(uri.String(), &covers); err != nil {
+			return nil, err
+		}
+
+		for _, cover := range covers.Data {
+			if cover.Attributes.FileName == "" {
+				continue
+			}
+
+			artwork = append(artwork, types.Artwork{
+				Type:       "poster",
+				Img:        fmt.Sprintf("%s/covers/%s/%s", p.Uploads, id, cover.Attributes.FileName),
+				ProviderID: p.Id,
+			})
+		}
+
+		if len(covers.Data) == 0 || offset+coverLimit >= covers.Total {
+			break
+		}
+	}
+
+	return artwork, nil
+}
+
+// get sends a GET request to the API and decodes the JSON response into v.
+func (p *MangaDexInformationProvider) get(uri string, v interface{}) error {
+	resp, err := p.Request(request.Options{
+		URL:    uri,
+		Method: "GET",
+	}, &p.NeedsProxy)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(resp.Response.Body)
+	resp.Response.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.Response.StatusCode != 200 {
+		return fmt.Errorf("unexpected status code: %d", resp.Response.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		fmt.Printf("JSON parsing error: %v\nResponse: %s\n", err, string(body))
+		return fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	return nil
 }
 
 func (p *MangaDexInformationProvider) GetSharedArea() types.MediaInfoKeys {
@@ -33,9 +165,87 @@ func (p *MangaDexInformationProvider) GetSharedArea() types.MediaInfoKeys {
 }
 
 func (p *MangaDexInformationProvider) ProxyCheck() (bool, error) {
-	return false, nil
+	return request.CheckProxy(p.Id, p.Api+"/ping")
 }
 
-func (p *MangaDexInformationProvider) Request(config http.Request, proxyRequest *bool) (request.Response, error) {
+func (p *MangaDexInformationProvider) Request(config request.Options, proxyRequest *bool) (request.Response, error) {
 	return p.BaseInformationProvider.Request(config, proxyRequest)
 }
+
+// extractSynonyms returns every alternative title followed by the main titles.
+func extractSynonyms(attributes MangaAttributes) []string {
+	var synonyms []string
+	for _, titleMap := range attributes.AltTitles {
+		for _, title := range titleMap {
+			synonyms = append(synonyms, title)
+		}
+	}
+	for _, title := range attributes.Title {
+		synonyms = append(synonyms, title)
+	}
+	return synonyms
+}
+
+// extractTagNames returns the English names of the tags in a group, such as "genre"
+// or "theme".
+func extractTagNames(tags []MangaTag, group string) []string {
+	var names []string
+	for _, tag := range tags {
+		if tag.Attributes.Group != group {
+			continue
+		}
+		if name, exists := tag.Attributes.Name["en"]; exists {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type MangaDexManga struct {
+	Result string `json:"result"`
+	Data   struct {
+		ID         string          `json:"id"`
+		Attributes MangaAttributes `json:"attributes"`
+	} `json:"data"`
+}
+
+type MangaAttributes struct {
+	Title     map[string]string   `json:"title"`
+	AltTitles []map[string]string `json:"altTitles"`
+	Tags      []MangaTag          `json:"tags"`
+}
+
+type MangaTag struct {
+	Attributes struct {
+		Name  map[string]string `json:"name"`
+		Group string            `json:"group"`
+	} `json:"attributes"`
+}
+
+type MangaDexCovers struct {
+	Result string  `json:"result"`
+	Data   []Cover `json:"data"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+	Total  int     `json:"total"`
+}
+
+type Cover struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Volume   *string `json:"volume"`
+		FileName string  `json:"fileName"`
+		Locale   string  `json:"locale"`
+	} `json:"attributes"`
+}
+
+type MangaDexStatistics struct {
+	Result     string `json:"result"`
+	Statistics map[string]struct {
+		Rating struct {
+			Average  *float64 `json:"average"`
+			Bayesian float64  `json:"bayesian"`
+		} `json:"rating"`
+		Follows int `json:"follows"`
+	} `json:"statistics"`
+}
@@ -6,20 +6,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type MangaDexProvider struct {
 	types.BaseMangaProvider
 	Api string
+	// Languages are the translated languages requested from the chapter feed.
+	Languages []string
 }
 
+// feedLimit is the maximum page size MangaDex allows for the chapter feed.
+const feedLimit = 500
+
 func NewMangaDexProvider() *MangaDexProvider {
 	return &MangaDexProvider{
 		BaseMangaProvider: types.BaseMangaProvider{
 			RateLimit:          250,
+			UserAgent:          "Anify (https://github.com/Eltik/Anify-Go)",
 			Id:                 "mangadex",
 			Url:                "https://mangadex.org",
 			Formats:            []types.Format{types.FormatManga, types.FormatOneShot},
@@ -27,7 +35,8 @@ func NewMangaDexProvider() *MangaDexProvider {
 			NeedsProxy:         true,
 			UseGoogleTranslate: false,
 		},
-		Api: "https://api.mangadex.org",
+		Api:       "https://api.mangadex.org",
+		Languages: []string{"en"},
 	}
 }
 
@@ -47,8 +56,8 @@ func (p *MangaDexProvider) Search(query string, format types.Format, year int) (
 		q.Add("includes[]", "cover_art")
 		uri.RawQuery = q.Encode()
 
-		resp, err := p.Request(http.Request{
-			URL:    uri,
+		resp, err := p.Request(request.Options{
+			URL:    uri.String(),
 			Method: "GET",
 		}, &p.NeedsProxy)
 		if err != nil {
@@ -144,27 +153,190 @@ func extractTitle(attributes Attributes) string {
 	return ""
 }
 
+// FetchChapters pages through the manga feed and returns one chapter per chapter
+// number and language in ascending order. When several groups scanlated the same
+// chapter only the first one in the feed is kept. External chapters without hosted
+// pages, and chapters in a language that was not requested, are skipped.
 func (p *MangaDexProvider) FetchChapters(id string) ([]types.Chapter, error) {
-	return nil, nil
+	var chapters []types.Chapter
+	seen := make(map[string]bool)
+
+	languages := make(map[string]bool)
+	for _, language := range p.Languages {
+		languages[language] = true
+	}
+
+	for offset := 0; ; offset += feedLimit {
+		uri, _ := url.Parse(p.Api + "/manga/" + id + "/feed")
+		q := uri.Query()
+
+		q.Set("limit", strconv.Itoa(feedLimit))
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("order[volume]", "asc")
+		q.Set("order[chapter]", "asc")
+		q.Set("includeFutureUpdates", "0")
+		q.Add("contentRating[]", "safe")
+		q.Add("contentRating[]", "suggestive")
+		q.Add("contentRating[]", "erotica")
+		q.Add("contentRating[]", "pornographic")
+		for _, language := range p.Languages {
+			q.Add("translatedLanguage[]", language)
+		}
+		uri.RawQuery = q.Encode()
+
+		resp, err := p.Request(request.Options{
+			URL:    uri.String(),
+			Method: "GET",
+		}, &p.NeedsProxy)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(resp.Response.Body)
+		resp.Response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading response body: %w", err)
+		}
+
+		if resp.Response.StatusCode != 200 {
+			return nil, fmt.Errorf("unexpected status code: %d", resp.Response.StatusCode)
+		}
+
+		var feed MangaDexFeed
+		if err := json.Unmarshal(body, &feed); err != nil {
+			return nil, fmt.Errorf("error parsing chapter feed at offset %d: %w", offset, err)
+		}
+
+		for _, chapter := range feed.Data {
+			if chapter.Attributes.ExternalURL != nil || chapter.Attributes.Pages == 0 {
+				continue
+			}
+			if len(languages) > 0 && !languages[chapter.Attributes.TranslatedLanguage] {
+				continue
+			}
+
+			// Oneshots have no chapter number, so they are keyed by ID instead.
+			key := chapter.ID
+			if chapter.Attributes.Chapter != nil {
+				key = chapter.Attributes.TranslatedLanguage + "/" + *chapter.Attributes.Chapter
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			chapters = append(chapters, toChapter(chapter))
+		}
+
+		if len(feed.Data) == 0 || offset+feedLimit >= feed.Total {
+			break
+		}
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Number < chapters[j].Number
+	})
+
+	return chapters, nil
+}
+
+func toChapter(chapter FeedChapter) types.Chapter {
+	var number float64
+	if chapter.Attributes.Chapter != nil {
+		number, _ = strconv.ParseFloat(*chapter.Attributes.Chapter, 64)
+	}
+
+	title := ""
+	if chapter.Attributes.Title != nil {
+		title = strings.TrimSpace(*chapter.Attributes.Title)
+	}
+	if title == "" {
+		if chapter.Attributes.Chapter != nil {
+			title = "Chapter " + *chapter.Attributes.Chapter
+		} else {
+			title = "Oneshot"
+		}
+	}
+
+	var updatedAt *int64
+	if t, err := time.Parse(time.RFC3339, chapter.Attributes.UpdatedAt); err == nil {
+		millis := t.UnixMilli()
+		updatedAt = &millis
+	}
+
+	return types.Chapter{
+		ID:        chapter.ID,
+		Title:     title,
+		Number:    number,
+		UpdatedAt: updatedAt,
+	}
 }
 
 func (p *MangaDexProvider) FetchRecent() ([]types.Manga, error) {
 	return nil, nil
 }
 
-func (p *MangaDexProvider) FetchPages(id string, proxy bool, chapter *types.Chapter) (interface{}, error) {
-	return nil, nil
+// FetchPages resolves the at-home server for a chapter ID and returns its page
+// URLs in reading order. dataSaver picks the compressed images over the originals.
+func (p *MangaDexProvider) FetchPages(id string, proxy bool, dataSaver bool) ([]types.Page, error) {
+	uri, _ := url.Parse(p.Api + "/at-home/server/" + id)
+
+	resp, err := p.Request(request.Options{
+		URL:    uri.String(),
+		Method: "GET",
+	}, &proxy)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Response.Body.Close()
+
+	if resp.Response.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.Response.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	var atHome MangaDexAtHome
+	if err := json.Unmarshal(body, &atHome); err != nil {
+		fmt.Printf("JSON parsing error: %v\nResponse: %s\n", err, string(body))
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+
+	if atHome.BaseURL == "" || atHome.Chapter.Hash == "" {
+		return nil, fmt.Errorf("no at-home server found for chapter: %s", id)
+	}
+
+	quality, files := "data", atHome.Chapter.Data
+	if dataSaver {
+		quality, files = "data-saver", atHome.Chapter.DataSaver
+	}
+
+	pages := make([]types.Page, 0, len(files))
+	for i, file := range files {
+		pages = append(pages, types.Page{
+			URL:   fmt.Sprintf("%s/%s/%s/%s", atHome.BaseURL, quality, atHome.Chapter.Hash, file),
+			Index: i,
+			Headers: map[string]string{
+				"Referer": p.Url,
+			},
+		})
+	}
+
+	return pages, nil
 }
 
 func (p *MangaDexProvider) ProxyCheck() (bool, error) {
-	return false, nil
+	return request.CheckProxy(p.Id, p.Api+"/ping")
 }
 
 func (p *MangaDexProvider) PadNum(number string, places int) string {
 	return p.BaseMangaProvider.PadNum(number, places)
 }
 
-func (p *MangaDexProvider) Request(config http.Request, proxyRequest *bool) (request.Response, error) {
+func (p *MangaDexProvider) Request(config request.Options, proxyRequest *bool) (request.Response, error) {
 	return p.BaseMangaProvider.Request(config, proxyRequest)
 }
 
@@ -193,3 +365,43 @@ type Relationship struct {
 	Related    string                 `json:"related"`
 	Attributes map[string]interface{} `json:"attributes"`
 }
+
+type MangaDexFeed struct {
+	Result   string        `json:"result"`
+	Response string        `json:"response"`
+	Data     []FeedChapter `json:"data"`
+	Limit    int           `json:"limit"`
+	Offset   int           `json:"offset"`
+	Total    int           `json:"total"`
+}
+
+type FeedChapter struct {
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	Attributes    ChapterAttributes `json:"attributes"`
+	Relationships []Relationship    `json:"relationships"`
+}
+
+type ChapterAttributes struct {
+	Volume             *string `json:"volume"`
+	Chapter            *string `json:"chapter"`
+	Title              *string `json:"title"`
+	TranslatedLanguage string  `json:"translatedLanguage"`
+	ExternalURL        *string `json:"externalUrl"`
+	PublishAt          string  `json:"publishAt"`
+	ReadableAt         string  `json:"readableAt"`
+	CreatedAt          string  `json:"createdAt"`
+	UpdatedAt          string  `json:"updatedAt"`
+	Pages              int     `json:"pages"`
+	Version            int     `json:"version"`
+}
+
+type MangaDexAtHome struct {
+	Result  string `json:"result"`
+	BaseURL string `json:"baseUrl"`
+	Chapter struct {
+		Hash      string   `json:"hash"`
+		Data      []string `json:"data"`
+		DataSaver []string `json:"dataSaver"`
+	} `json:"chapter"`
+}
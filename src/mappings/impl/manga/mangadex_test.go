@@ -0,0 +1,251 @@
+package manga
+
+import (
+	"anify/eltik/go/src/lib/impl/request/requesttest"
+	"anify/eltik/go/src/types"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newFeedServer serves testdata/mangadex_feed_<offset>.json for the chapter feed
+// of id and records the translated languages of every request.
+func newFeedServer(t *testing.T, id string, languages *[][]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manga/"+id+"/feed" {
+			http.NotFound(w, r)
+			return
+		}
+		*languages = append(*languages, r.URL.Query()["translatedLanguage[]"])
+
+		data, err := os.ReadFile(filepath.Join("testdata", "mangadex_feed_"+r.URL.Query().Get("offset")+".json"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestProvider(api string, languages ...string) *MangaDexProvider {
+	provider := NewMangaDexProvider()
+	provider.Id = "mangadex-test"
+	provider.RateLimit = 0
+	provider.NeedsProxy = false
+	provider.Api = api
+	provider.Languages = languages
+	return provider
+}
+
+func TestFetchChapters(t *testing.T) {
+	var languages [][]string
+	server := newFeedServer(t, "manga-id", &languages)
+
+	chapters, err := newTestProvider(server.URL, "en", "ja").FetchChapters("manga-id")
+	if err != nil {
+		t.Fatalf("FetchChapters: %v", err)
+	}
+
+	// Both feed pages are requested, with every language.
+	want := [][]string{{"en", "ja"}, {"en", "ja"}}
+	if !reflect.DeepEqual(languages, want) {
+		t.Errorf("translatedLanguage[] = %v, want %v", languages, want)
+	}
+
+	var ids []string
+	for _, chapter := range chapters {
+		ids = append(ids, chapter.ID)
+	}
+	wantIds := []string{"oneshot", "c1-en-a", "c1-ja", "c2-en", "c3-en"}
+	if !reflect.DeepEqual(ids, wantIds) {
+		t.Fatalf("chapter IDs = %v, want %v", ids, wantIds)
+	}
+
+	titles := map[string]string{
+		"oneshot": "Oneshot",
+		"c1-en-a": "Romance Dawn",
+		"c1-ja":   "Chapter 1",
+		"c3-en":   "Chapter 3",
+	}
+	for _, chapter := range chapters {
+		if title, ok := titles[chapter.ID]; ok && chapter.Title != title {
+			t.Errorf("%s title = %q, want %q", chapter.ID, chapter.Title, title)
+		}
+	}
+
+	if chapters[3].Number != 2 {
+		t.Errorf("c2-en number = %v, want 2", chapters[3].Number)
+	}
+
+	wantUpdated := time.Date(2023, 2, 1, 3, 0, 0, 0, time.UTC).UnixMilli()
+	if chapters[3].UpdatedAt == nil || *chapters[3].UpdatedAt != wantUpdated {
+		t.Errorf("c2-en updatedAt = %v, want %d", chapters[3].UpdatedAt, wantUpdated)
+	}
+	if chapters[4].UpdatedAt != nil {
+		t.Errorf("c3-en updatedAt = %d, want nil for an invalid date", *chapters[4].UpdatedAt)
+	}
+}
+
+func TestFetchChaptersSingleLanguage(t *testing.T) {
+	var languages [][]string
+	server := newFeedServer(t, "manga-id", &languages)
+
+	chapters, err := newTestProvider(server.URL, "en").FetchChapters("manga-id")
+	if err != nil {
+		t.Fatalf("FetchChapters: %v", err)
+	}
+
+	for _, chapter := range chapters {
+		if chapter.ID == "c1-ja" || chapter.ID == "c1-fr" {
+			t.Errorf("chapter %s in an unrequested language was kept", chapter.ID)
+		}
+	}
+	if len(chapters) != 4 {
+		t.Errorf("got %d chapters, want 4", len(chapters))
+	}
+}
+
+func TestFetchChaptersInvalidJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{not json"))
+	}))
+	defer server.Close()
+
+	if _, err := newTestProvider(server.URL, "en").FetchChapters("manga-id"); err == nil {
+		t.Fatal("FetchChapters returned no error for an invalid feed")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	requesttest.UseCassette(t, "mangadex_search.json")
+
+	results, err := NewMangaDexProvider().Search("One Piece", types.FormatManga, 1997)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	found := false
+	for _, result := range results {
+		if result.ProviderId != "mangadex" || result.ID == "" {
+			t.Errorf("result = %+v", result)
+		}
+		if result.ID == requesttest.OnePiece {
+			found = true
+			if result.Title != "One Piece" || result.Year != 1997 || result.Format != types.FormatManga {
+				t.Errorf("One Piece result = %+v", result)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("Search did not return %s", requesttest.OnePiece)
+	}
+}
+
+func TestFetchChaptersReplay(t *testing.T) {
+	requesttest.UseCassette(t, "mangadex_feed.json")
+
+	chapters, err := NewMangaDexProvider().FetchChapters(requesttest.OnePiece)
+	if err != nil {
+		t.Fatalf("FetchChapters: %v", err)
+	}
+	if len(chapters) == 0 {
+		t.Fatal("FetchChapters returned no chapters")
+	}
+
+	seen := make(map[float64]bool)
+	for i, chapter := range chapters {
+		if chapter.ID == "" || chapter.Title == "" {
+			t.Errorf("chapter = %+v", chapter)
+		}
+		if i > 0 && chapter.Number < chapters[i-1].Number {
+			t.Errorf("chapter %v comes after %v", chapter.Number, chapters[i-1].Number)
+		}
+		if seen[chapter.Number] {
+			t.Errorf("chapter %v is listed twice", chapter.Number)
+		}
+		seen[chapter.Number] = true
+	}
+}
+
+// newAtHomeServer serves body as the at-home server of the chapter id.
+func newAtHomeServer(t *testing.T, id, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/at-home/server/"+id {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestFetchPages(t *testing.T) {
+	server := newAtHomeServer(t, "chapter-id", `{
+		"result": "ok",
+		"baseUrl": "https://uploads.example.org",
+		"chapter": {
+			"hash": "abc123",
+			"data": ["1-full.png", "2-full.png"],
+			"dataSaver": ["1-small.jpg", "2-small.jpg"]
+		}
+	}`)
+	provider := newTestProvider(server.URL, "en")
+
+	tests := []struct {
+		dataSaver bool
+		want      []string
+	}{
+		{false, []string{
+			"https://uploads.example.org/data/abc123/1-full.png",
+			"https://uploads.example.org/data/abc123/2-full.png",
+		}},
+		{true, []string{
+			"https://uploads.example.org/data-saver/abc123/1-small.jpg",
+			"https://uploads.example.org/data-saver/abc123/2-small.jpg",
+		}},
+	}
+	for _, test := range tests {
+		pages, err := provider.FetchPages("chapter-id", false, test.dataSaver)
+		if err != nil {
+			t.Fatalf("FetchPages(dataSaver=%v): %v", test.dataSaver, err)
+		}
+
+		var urls []string
+		for i, page := range pages {
+			urls = append(urls, page.URL)
+			if page.Index != i {
+				t.Errorf("page %d index = %d", i, page.Index)
+			}
+			if page.Headers["Referer"] != provider.Url {
+				t.Errorf("page %d Referer = %q, want %q", i, page.Headers["Referer"], provider.Url)
+			}
+		}
+		if !reflect.DeepEqual(urls, test.want) {
+			t.Errorf("FetchPages(dataSaver=%v) = %v, want %v", test.dataSaver, urls, test.want)
+		}
+	}
+}
+
+func TestFetchPagesMissingHash(t *testing.T) {
+	server := newAtHomeServer(t, "chapter-id", `{"result": "ok", "baseUrl": "https://uploads.example.org", "chapter": {}}`)
+
+	if _, err := newTestProvider(server.URL, "en").FetchPages("chapter-id", false, false); err == nil {
+		t.Fatal("FetchPages returned no error without a chapter hash")
+	}
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	baseProviders "anify/eltik/go/src/mappings/impl/base"
+	informationProviders "anify/eltik/go/src/mappings/impl/information"
 	mangaProviders "anify/eltik/go/src/mappings/impl/manga"
 	types "anify/eltik/go/src/types"
 )
@@ -27,3 +28,11 @@ func GetMangaProviders() *[]types.MangaProvider {
 
 	return &providers
 }
+
+func GetInformationProviders() *[]types.InformationProvider[types.Media, types.MediaInfo] {
+	providers := []types.InformationProvider[types.Media, types.MediaInfo]{
+		informationProviders.NewMangaDexInformationProvider(),
+	}
+
+	return &providers
+}
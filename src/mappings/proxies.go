@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"anify/eltik/go/src/lib/impl/request"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// proxyChecker is the part of every provider type that CheckProxies uses.
+type proxyChecker interface {
+	GetID() string
+	ProxyCheck() (bool, error)
+}
+
+// DefaultProxyCheckInterval is how often RunProxyChecks probes the proxies.
+const DefaultProxyCheckInterval = 10 * time.Minute
+
+// CheckProxies runs ProxyCheck once per provider ID, which probes every proxy loaded
+// for it and records the results in the proxy health table. Providers without any
+// proxies are skipped.
+func CheckProxies() {
+	var checkers []proxyChecker
+	for _, provider := range *GetBaseProviders() {
+		checkers = append(checkers, provider)
+	}
+	for _, provider := range *GetAnimeProviders() {
+		checkers = append(checkers, provider)
+	}
+	for _, provider := range *GetMangaProviders() {
+		checkers = append(checkers, provider)
+	}
+	for _, provider := range *GetInformationProviders() {
+		checkers = append(checkers, provider)
+	}
+
+	checked := make(map[string]bool)
+	for _, checker := range checkers {
+		if checked[checker.GetID()] {
+			continue
+		}
+		checked[checker.GetID()] = true
+
+		working, err := checker.ProxyCheck()
+		if errors.Is(err, request.ErrNoProxies) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Proxy check for %s failed: %v", checker.GetID(), err)
+			continue
+		}
+		if !working {
+			log.Printf("Proxy check for %s: no proxy is working", checker.GetID())
+		}
+	}
+}
+
+// RunProxyChecks calls CheckProxies straight away and then every interval until the
+// context is cancelled.
+func RunProxyChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		CheckProxies()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// requireAdmin only lets requests through that carry ADMIN_KEY, either as a bearer
+// token or in the X-Admin-Key header. Admin routes are disabled when ADMIN_KEY is
+// not set.
+func requireAdmin(c *fiber.Ctx) error {
+	key := os.Getenv("ADMIN_KEY")
+	if key == "" {
+		return fiber.NewError(fiber.StatusForbidden, "admin API is disabled, set ADMIN_KEY to enable it")
+	}
+
+	provided := c.Get("X-Admin-Key")
+	if provided == "" {
+		provided = strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid admin key")
+	}
+
+	return c.Next()
+}
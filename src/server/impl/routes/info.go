@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"anify/eltik/go/src/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Info returns a stored anime or manga. The type query parameter is optional; without
+// it both tables are checked.
+func (h *Handler) Info(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	typesToCheck := []types.Type{types.TypeAnime, types.TypeManga}
+	if c.Query("type") != "" {
+		type_, err := parseType(c.Query("type"))
+		if err != nil {
+			return err
+		}
+		typesToCheck = []types.Type{type_}
+	}
+
+	for _, type_ := range typesToCheck {
+		media, err := h.Repo.Get(c.UserContext(), id, type_)
+		if err != nil {
+			return err
+		}
+		if media != nil {
+			return c.JSON(media)
+		}
+	}
+
+	return fiber.NewError(fiber.StatusNotFound, "media not found")
+}
+
+// Provenance returns which provider supplied each field of a stored anime or manga,
+// when it was fetched, the value it sent and whether it set, merged into, overwrote
+// or left the field. The most recent value that changed a field comes last. Pass
+// ?field=description to only return one field.
+func (h *Handler) Provenance(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	typesToCheck := []types.Type{types.TypeAnime, types.TypeManga}
+	if c.Query("type") != "" {
+		type_, err := parseType(c.Query("type"))
+		if err != nil {
+			return err
+		}
+		typesToCheck = []types.Type{type_}
+	}
+
+	for _, type_ := range typesToCheck {
+		media, err := h.Repo.Get(c.UserContext(), id, type_)
+		if err != nil {
+			return err
+		}
+
+		var provenance types.Provenance
+		switch media := media.(type) {
+		case *types.Anime:
+			provenance = media.Provenance
+		case *types.Manga:
+			provenance = media.Provenance
+		default:
+			continue
+		}
+
+		if provenance == nil {
+			provenance = types.Provenance{}
+		}
+		if field := c.Query("field"); field != "" {
+			provenance = types.Provenance{field: provenance[field]}
+		}
+
+		return c.JSON(fiber.Map{
+			"id":         id,
+			"type":       type_,
+			"provenance": provenance,
+		})
+	}
+
+	return fiber.NewError(fiber.StatusNotFound, "media not found")
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"anify/eltik/go/src/database"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Jobs lists the most recently updated mapping jobs. Supports ?state= and ?limit=.
+func (h *Handler) Jobs(c *fiber.Ctx) error {
+	state := database.JobState(strings.ToLower(c.Query("state")))
+	switch state {
+	case "", database.JobQueued, database.JobRunning, database.JobDone, database.JobFailed:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "invalid state: "+string(state))
+	}
+
+	limit, err := parseInt(c.Query("limit"), 50)
+	if err != nil {
+		return err
+	}
+	if limit < 1 || limit > 500 {
+		return fiber.NewError(fiber.StatusBadRequest, "limit must be between 1 and 500")
+	}
+
+	jobs, err := h.Repo.ListJobs(c.UserContext(), state, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(jobs)
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"anify/eltik/go/src/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MappingReport returns the match report of the last mapping run for an ID: every
+// provider search, the candidates it returned with their scores, and the rule that
+// accepted or rejected each one. The type query parameter is optional.
+func (h *Handler) MappingReport(c *fiber.Ctx) error {
+	var type_ types.Type
+	if c.Query("type") != "" {
+		parsed, err := parseType(c.Query("type"))
+		if err != nil {
+			return err
+		}
+		type_ = parsed
+	}
+
+	report, err := h.Repo.GetMatchReport(c.UserContext(), c.Params("id"), type_)
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		return fiber.NewError(fiber.StatusNotFound, "no match report found")
+	}
+
+	return c.JSON(report)
+}
@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/lib/impl/mappings"
+	providers "anify/eltik/go/src/mappings"
+	"anify/eltik/go/src/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// overrideRequest is the body of POST /admin/overrides.
+type overrideRequest struct {
+	MediaID    string `json:"mediaId"`
+	Type       string `json:"type"`
+	ProviderID string `json:"providerId"`
+	// ProviderMediaID is the provider's ID to pin or block. It must be empty for
+	// "none".
+	ProviderMediaID string `json:"providerMediaId"`
+	Kind            string `json:"kind"`
+	Note            string `json:"note"`
+}
+
+// CreateOverride stores a mapping override and applies it to the stored entry
+// straight away, if there is one.
+func (h *Handler) CreateOverride(c *fiber.Ctx) error {
+	var body overrideRequest
+	if err := c.BodyParser(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body: "+err.Error())
+	}
+
+	if body.MediaID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "mediaId is required")
+	}
+
+	type_, err := parseType(body.Type)
+	if err != nil {
+		return err
+	}
+
+	kind := database.OverrideKind(body.Kind)
+	switch kind {
+	case database.OverridePin, database.OverrideBlock:
+		if body.ProviderMediaID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "providerMediaId is required for "+body.Kind)
+		}
+	case database.OverrideNone:
+		if body.ProviderMediaID != "" {
+			return fiber.NewError(fiber.StatusBadRequest, "providerMediaId must be empty for none")
+		}
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "invalid kind: "+body.Kind)
+	}
+
+	if !isMappingProvider(body.ProviderID, type_) {
+		return fiber.NewError(fiber.StatusBadRequest, "unknown provider: "+body.ProviderID)
+	}
+
+	override, err := h.Repo.SetMappingOverride(c.UserContext(), database.MappingOverride{
+		MediaID:         body.MediaID,
+		Type:            type_,
+		ProviderID:      body.ProviderID,
+		ProviderMediaID: body.ProviderMediaID,
+		Kind:            kind,
+		Note:            body.Note,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := mappings.ApplyOverrides(c.UserContext(), h.Repo, override.MediaID, override.Type); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(override)
+}
+
+// ListOverrides returns the mapping overrides. Supports ?mediaId= and ?type=.
+func (h *Handler) ListOverrides(c *fiber.Ctx) error {
+	var type_ types.Type
+	if c.Query("type") != "" {
+		parsed, err := parseType(c.Query("type"))
+		if err != nil {
+			return err
+		}
+		type_ = parsed
+	}
+
+	overrides, err := h.Repo.ListMappingOverrides(c.UserContext(), c.Query("mediaId"), type_)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(overrides)
+}
+
+// DeleteOverride removes a mapping override. The stored mappings are left as they
+// are and ApplyOverrides is not run again, as removing an override never takes a
+// mapping away: the mapping of a removed pin is kept. Removing a block or "none"
+// does not add a mapping back either, since stored entries are not searched again;
+// pin the provider's ID to map it.
+func (h *Handler) DeleteOverride(c *fiber.Ctx) error {
+	id, err := parseID(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	override, err := h.Repo.DeleteMappingOverride(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	if override == nil {
+		return fiber.NewError(fiber.StatusNotFound, "override not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// isMappingProvider reports whether a provider ID belongs to one of the mapping
+// providers of a type.
+func isMappingProvider(providerId string, type_ types.Type) bool {
+	if type_ == types.TypeAnime {
+		for _, provider := range *providers.GetAnimeProviders() {
+			if provider.GetID() == providerId {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, provider := range *providers.GetMangaProviders() {
+		if provider.GetID() == providerId {
+			return true
+		}
+	}
+	return false
+}
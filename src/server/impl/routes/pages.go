@@ -0,0 +1,30 @@
+package routes
+
+import (
+	providers "anify/eltik/go/src/mappings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Pages returns the page images of a chapter from the given manga provider.
+func (h *Handler) Pages(c *fiber.Ctx) error {
+	providerId := c.Params("providerId")
+
+	for _, provider := range *providers.GetMangaProviders() {
+		if provider.GetID() != providerId {
+			continue
+		}
+
+		pages, err := provider.FetchPages(c.Params("id"), c.QueryBool("proxy", true), c.QueryBool("dataSaver", false))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadGateway, err.Error())
+		}
+		if len(pages) == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "no pages found")
+		}
+
+		return c.JSON(pages)
+	}
+
+	return fiber.NewError(fiber.StatusNotFound, "unknown provider: "+providerId)
+}
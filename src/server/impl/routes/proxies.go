@@ -0,0 +1,18 @@
+package routes
+
+import (
+	proxies "anify/eltik/go/src/lib/impl/proxies"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Proxies returns the health of every proxy that has been used, optionally limited
+// to one provider with ?provider=.
+func (h *Handler) Proxies(c *fiber.Ctx) error {
+	health := proxies.GetHealth(c.Query("provider"))
+	if health == nil {
+		health = []proxies.Health{}
+	}
+
+	return c.JSON(health)
+}
@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/lib/impl/webhooks"
+	providers "anify/eltik/go/src/mappings"
+	"anify/eltik/go/src/types"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler holds the dependencies shared by the route handlers.
+type Handler struct {
+	Repo     database.Repository
+	Webhooks *webhooks.Dispatcher
+}
+
+// Register mounts every API route on the app.
+func Register(app *fiber.App, repo database.Repository) {
+	h := &Handler{
+		Repo:     repo,
+		Webhooks: webhooks.New(repo, webhooks.DefaultConfig),
+	}
+
+	app.Get("/info/:id", h.Info)
+	app.Get("/info/:id/provenance", h.Provenance)
+	app.Get("/search/:type/:query", h.Search)
+	app.Get("/search-advanced", h.SearchAdvanced)
+	app.Get("/seasonal/:type", h.Seasonal)
+	app.Get("/pages/:providerId/:id", h.Pages)
+	app.Get("/proxies", h.Proxies)
+	app.Get("/jobs", h.Jobs)
+	app.Get("/mappings/:id/report", h.MappingReport)
+	app.Get("/events/stream", h.StreamSSE)
+	app.Get("/events/ws", h.UpgradeStream, websocket.New(h.StreamWebSocket))
+
+	admin := app.Group("/admin", requireAdmin)
+	admin.Post("/webhooks", h.CreateWebhook)
+	admin.Get("/webhooks", h.ListWebhooks)
+	admin.Delete("/webhooks/:id", h.DeleteWebhook)
+	admin.Post("/webhooks/:id/test", h.TestWebhook)
+	admin.Get("/webhooks/:id/deliveries", h.WebhookDeliveries)
+	admin.Post("/overrides", h.CreateOverride)
+	admin.Get("/overrides", h.ListOverrides)
+	admin.Delete("/overrides/:id", h.DeleteOverride)
+}
+
+// parseType converts a path or query value such as "anime" into a types.Type.
+func parseType(value string) (types.Type, error) {
+	switch types.Type(strings.ToUpper(value)) {
+	case types.TypeAnime:
+		return types.TypeAnime, nil
+	case types.TypeManga:
+		return types.TypeManga, nil
+	}
+
+	return "", fiber.NewError(fiber.StatusBadRequest, "invalid type: "+value)
+}
+
+// parseFormats reads a comma separated list of formats, falling back to every
+// format that belongs to the given type.
+func parseFormats(value string, type_ types.Type) []types.Format {
+	if value == "" {
+		if type_ == types.TypeAnime {
+			return []types.Format{types.FormatTV, types.FormatTVShort, types.FormatMovie, types.FormatSpecial, types.FormatOVA, types.FormatONA}
+		}
+		return []types.Format{types.FormatManga, types.FormatOneShot, types.FormatNovel}
+	}
+
+	var formats []types.Format
+	for _, format := range strings.Split(value, ",") {
+		format = strings.TrimSpace(format)
+		if format != "" {
+			formats = append(formats, types.Format(strings.ToUpper(format)))
+		}
+	}
+	return formats
+}
+
+// parseList reads a comma separated query value, ignoring empty entries.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseInt reads an integer query value, returning fallback when it is missing.
+func parseInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid number: "+value)
+	}
+	return i, nil
+}
+
+// baseProviderFor returns the first base provider that supports one of the formats.
+func baseProviderFor(formats []types.Format) (types.BaseProvider, error) {
+	for _, provider := range *providers.GetBaseProviders() {
+		for _, supported := range provider.GetFormats() {
+			for _, format := range formats {
+				if supported == format {
+					return provider, nil
+				}
+			}
+		}
+	}
+
+	return nil, fiber.NewError(fiber.StatusBadRequest, "no base provider supports the requested formats")
+}
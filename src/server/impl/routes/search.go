@@ -0,0 +1,307 @@
+package routes
+
+import (
+	"anify/eltik/go/src/database"
+	events "anify/eltik/go/src/lib"
+	"anify/eltik/go/src/types"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Search looks the query up in the local catalog and only falls back to the base
+// provider when the first page of stored matches is empty.
+func (h *Handler) Search(c *fiber.Ctx) error {
+	type_, err := parseType(c.Params("type"))
+	if err != nil {
+		return err
+	}
+
+	page, err := parseInt(c.Query("page"), 1)
+	if err != nil {
+		return err
+	}
+
+	perPage, err := parseInt(c.Query("perPage"), 25)
+	if err != nil {
+		return err
+	}
+
+	opts := database.SearchOptions{
+		Query:   c.Params("query"),
+		Type:    type_,
+		Page:    page,
+		PerPage: perPage,
+	}
+	if c.Query("formats") != "" {
+		opts.Formats = parseFormats(c.Query("formats"), type_)
+	}
+
+	stored, fallback, err := h.searchStored(c, opts)
+	if err != nil {
+		return err
+	}
+	if !fallback {
+		return c.JSON(stored)
+	}
+
+	formats := parseFormats(c.Query("formats"), type_)
+
+	provider, err := baseProviderFor(formats)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	results, err := provider.Search(c.Params("query"), type_, formats, page, perPage)
+	publishSearchLoad(provider, c.Params("query"), type_, len(results), start, err)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(fromMediaInfo(results, provider.GetID()))
+}
+
+// SearchAdvanced searches the local catalog with format, genre, tag, season, year
+// and status filters, falling back to the base provider like Search.
+func (h *Handler) SearchAdvanced(c *fiber.Ctx) error {
+	type_, err := parseType(c.Query("type", string(types.TypeAnime)))
+	if err != nil {
+		return err
+	}
+
+	page, err := parseInt(c.Query("page"), 1)
+	if err != nil {
+		return err
+	}
+
+	perPage, err := parseInt(c.Query("perPage"), 25)
+	if err != nil {
+		return err
+	}
+
+	year, err := parseInt(c.Query("year"), 0)
+	if err != nil {
+		return err
+	}
+
+	season := types.SeasonUnknown
+	if c.Query("season") != "" {
+		season = types.Season(strings.ToUpper(c.Query("season")))
+	}
+
+	genres := parseList(c.Query("genres"))
+	genresExcluded := parseList(c.Query("genresExcluded"))
+	tags := parseList(c.Query("tags"))
+	tagsExcluded := parseList(c.Query("tagsExcluded"))
+
+	opts := database.SearchOptions{
+		Query:          c.Query("query"),
+		Type:           type_,
+		Year:           year,
+		Season:         season,
+		Status:         types.Status(strings.ToUpper(c.Query("status"))),
+		Genres:         genres,
+		GenresExcluded: genresExcluded,
+		Tags:           tags,
+		TagsExcluded:   tagsExcluded,
+		Page:           page,
+		PerPage:        perPage,
+	}
+	if c.Query("formats") != "" {
+		opts.Formats = parseFormats(c.Query("formats"), type_)
+	}
+
+	stored, fallback, err := h.searchStored(c, opts)
+	if err != nil {
+		return err
+	}
+	if !fallback {
+		return c.JSON(stored)
+	}
+
+	formats := parseFormats(c.Query("formats"), type_)
+
+	provider, err := baseProviderFor(formats)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	results, err := provider.SearchAdvanced(
+		c.Query("query"),
+		type_,
+		formats,
+		page,
+		perPage,
+		genres,
+		genresExcluded,
+		season,
+		year,
+		tags,
+		tagsExcluded,
+	)
+	publishSearchLoad(provider, c.Query("query"), type_, len(results), start, err)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(fromMediaInfo(results, provider.GetID()))
+}
+
+// publishSearchLoad publishes a SearchLoadCompleted event for a provider search.
+func publishSearchLoad(provider types.BaseProvider, query string, type_ types.Type, results int, start time.Time, err error) {
+	event := events.SearchLoadCompleted{
+		ProviderID: provider.GetID(),
+		Query:      query,
+		Type:       string(type_),
+		Results:    results,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	events.Publish(event)
+}
+
+// searchStored runs a database search and reports whether the base provider
+// should be asked instead. That is only the case when the first page of the local
+// catalog is empty, so a later page never mixes local and provider results.
+func (h *Handler) searchStored(c *fiber.Ctx, opts database.SearchOptions) ([]searchResult, bool, error) {
+	stored, err := h.Repo.Search(c.UserContext(), opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := fromStored(stored)
+	if len(results) > 0 || opts.Page <= 1 {
+		return results, len(results) == 0, nil
+	}
+
+	first := opts
+	first.Page = 1
+	stored, err = h.Repo.Search(c.UserContext(), first)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return results, len(fromStored(stored)) == 0, nil
+}
+
+// Search result sources.
+const (
+	sourceLocal    = "local"
+	sourceProvider = "provider"
+)
+
+// searchResult is a single search hit. Stored entries and base provider results
+// are both returned in this shape; Source tells them apart.
+type searchResult struct {
+	ID              string       `json:"id"`
+	Slug            string       `json:"slug,omitempty"`
+	Type            types.Type   `json:"type"`
+	Format          types.Format `json:"format"`
+	Title           types.Title  `json:"title"`
+	Synonyms        []string     `json:"synonyms"`
+	CoverImage      *string      `json:"coverImage"`
+	BannerImage     *string      `json:"bannerImage"`
+	Description     *string      `json:"description"`
+	Status          *string      `json:"status"`
+	Season          types.Season `json:"season"`
+	Year            *int         `json:"year"`
+	Genres          []string     `json:"genres"`
+	Tags            []string     `json:"tags"`
+	CountryOfOrigin *string      `json:"countryOfOrigin"`
+	Rating          *float64     `json:"rating"`
+	Popularity      *float64     `json:"popularity"`
+	TotalEpisodes   *int         `json:"totalEpisodes,omitempty"`
+	TotalChapters   *int         `json:"totalChapters,omitempty"`
+	// Source is "local" for stored entries and "provider" for base provider results.
+	Source string `json:"source"`
+	// ProviderID is the base provider that answered, for provider results.
+	ProviderID string `json:"providerId,omitempty"`
+}
+
+// fromStored converts the []types.Anime or []types.Manga returned by a database
+// search.
+func fromStored(stored interface{}) []searchResult {
+	results := []searchResult{}
+
+	switch r := stored.(type) {
+	case []types.Anime:
+		for _, anime := range r {
+			result := fromMedia(anime.ToMedia())
+			result.TotalEpisodes = anime.TotalEpisodes
+			results = append(results, result)
+		}
+	case []types.Manga:
+		for _, manga := range r {
+			result := fromMedia(manga.ToMedia())
+			result.TotalChapters = manga.TotalChapters
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+func fromMedia(media types.Media) searchResult {
+	var status *string
+	if media.Status != nil {
+		s := string(*media.Status)
+		status = &s
+	}
+
+	return searchResult{
+		ID:              media.ID,
+		Slug:            media.Slug,
+		Type:            media.Type,
+		Format:          media.Format,
+		Title:           media.Title,
+		Synonyms:        media.Synonyms,
+		CoverImage:      media.CoverImage,
+		BannerImage:     media.BannerImage,
+		Description:     media.Description,
+		Status:          status,
+		Season:          media.Season,
+		Year:            media.Year,
+		Genres:          media.Genres,
+		Tags:            media.Tags,
+		CountryOfOrigin: media.CountryOfOrigin,
+		Rating:          media.AverageRating,
+		Popularity:      media.AveragePopularity,
+		Source:          sourceLocal,
+	}
+}
+
+// fromMediaInfo converts the results of a base provider search.
+func fromMediaInfo(infos []types.MediaInfo, providerId string) []searchResult {
+	results := make([]searchResult, 0, len(infos))
+	for _, info := range infos {
+		results = append(results, searchResult{
+			ID:              info.ID,
+			Type:            info.Type,
+			Format:          info.Format,
+			Title:           info.Title,
+			Synonyms:        info.Synonyms,
+			CoverImage:      info.CoverImage,
+			BannerImage:     info.BannerImage,
+			Description:     info.Description,
+			Status:          info.Status,
+			Season:          info.Season,
+			Year:            info.Year,
+			Genres:          info.Genres,
+			Tags:            info.Tags,
+			CountryOfOrigin: info.CountryOfOrigin,
+			Rating:          info.Rating,
+			Popularity:      info.Popularity,
+			TotalEpisodes:   info.TotalEpisodes,
+			TotalChapters:   info.TotalChapters,
+			Source:          sourceProvider,
+			ProviderID:      providerId,
+		})
+	}
+	return results
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	events "anify/eltik/go/src/lib"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Seasonal returns the trending, popular, top and seasonal lists for the given type.
+func (h *Handler) Seasonal(c *fiber.Ctx) error {
+	type_, err := parseType(c.Params("type"))
+	if err != nil {
+		return err
+	}
+
+	formats := parseFormats(c.Query("formats"), type_)
+
+	provider, err := baseProviderFor(formats)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	results, err := provider.GetSeasonal(type_, formats)
+
+	event := events.SeasonalLoadCompleted{
+		ProviderID: provider.GetID(),
+		Type:       string(type_),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	events.Publish(event)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(results)
+}
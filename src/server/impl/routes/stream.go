@@ -0,0 +1,242 @@
+package routes
+
+import (
+	events "anify/eltik/go/src/lib"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// streamBuffer is how many events may wait for a slow client. Further events are
+	// dropped for that client and it is told how many it missed.
+	streamBuffer = 64
+	// streamHeartbeat keeps idle connections open through proxies.
+	streamHeartbeat = 15 * time.Second
+	// droppedTopic is sent to a client after events were dropped for it.
+	droppedTopic = "stream.dropped"
+)
+
+// streamFilter limits which events a client receives. Empty fields match anything.
+type streamFilter struct {
+	topics    map[string]bool
+	mediaType string
+	provider  string
+}
+
+// parseStreamFilter reads ?events=, ?type= and ?provider=.
+func parseStreamFilter(query func(string, ...string) string) (streamFilter, error) {
+	filter := streamFilter{
+		mediaType: strings.ToUpper(query("type")),
+		provider:  query("provider"),
+	}
+
+	if list := parseList(query("events")); len(list) > 0 {
+		filter.topics = make(map[string]bool)
+		for _, topic := range list {
+			if !isTopic(topic) {
+				return streamFilter{}, fiber.NewError(fiber.StatusBadRequest, "unknown event: "+topic)
+			}
+			filter.topics[topic] = true
+		}
+	}
+
+	if filter.mediaType != "" {
+		if _, err := parseType(filter.mediaType); err != nil {
+			return streamFilter{}, err
+		}
+	}
+
+	return filter, nil
+}
+
+func (f streamFilter) match(event events.Event) bool {
+	if f.topics != nil && !f.topics[event.Topic()] {
+		return false
+	}
+	if f.mediaType != "" && events.MediaType(event) != f.mediaType {
+		return false
+	}
+	if f.provider != "" {
+		found := false
+		for _, id := range events.ProviderIDs(event) {
+			if id == f.provider {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// streamSubscriber buffers the events for one client. The bus never waits on it.
+type streamSubscriber struct {
+	events      chan events.Envelope
+	dropped     atomic.Int64
+	unsubscribe func()
+}
+
+func subscribeStream(filter streamFilter) *streamSubscriber {
+	s := &streamSubscriber{events: make(chan events.Envelope, streamBuffer)}
+
+	s.unsubscribe = events.SubscribeAll(func(event events.Event) {
+		if !filter.match(event) {
+			return
+		}
+
+		select {
+		case s.events <- events.NewEnvelope(event):
+		default:
+			s.dropped.Add(1)
+		}
+	})
+
+	return s
+}
+
+// streamMessage is what streamSubscriber.next returned.
+type streamMessage int
+
+const (
+	streamEvent streamMessage = iota
+	streamPing
+	streamClosed
+)
+
+// next waits for the next message to send: a notice about dropped events if there
+// were any, otherwise the next event, a ping when the heartbeat is due, or closed
+// once done is closed.
+func (s *streamSubscriber) next(heartbeat <-chan time.Time, done <-chan struct{}) (events.Envelope, streamMessage) {
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		return events.NewEnvelope(droppedEvent{Count: dropped}), streamEvent
+	}
+
+	select {
+	case envelope := <-s.events:
+		return envelope, streamEvent
+	case <-heartbeat:
+		return events.Envelope{}, streamPing
+	case <-done:
+		return events.Envelope{}, streamClosed
+	}
+}
+
+// StreamSSE relays bus events as Server-Sent Events. Supports ?events=, ?type= and
+// ?provider= filters.
+func (h *Handler) StreamSSE(c *fiber.Ctx) error {
+	filter, err := parseStreamFilter(c.Query)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		s := subscribeStream(filter)
+		defer s.unsubscribe()
+
+		ticker := time.NewTicker(streamHeartbeat)
+		defer ticker.Stop()
+
+		// Flush the headers straight away so clients know they are connected.
+		fmt.Fprint(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			// Disconnects are noticed when flushing, at the latest on the next ping.
+			envelope, message := s.next(ticker.C, nil)
+			if message == streamPing {
+				fmt.Fprint(w, ": ping\n\n")
+			} else {
+				data, err := json.Marshal(envelope.Data)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", envelope.ID, envelope.Event, data)
+			}
+
+			// A failed flush means the client went away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// UpgradeStream checks the filters and that the request is a WebSocket upgrade
+// before StreamWebSocket takes over.
+func (h *Handler) UpgradeStream(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	filter, err := parseStreamFilter(c.Query)
+	if err != nil {
+		return err
+	}
+
+	c.Locals("streamFilter", filter)
+	return c.Next()
+}
+
+// StreamWebSocket relays bus events as JSON envelopes over a WebSocket.
+func (h *Handler) StreamWebSocket(conn *websocket.Conn) {
+	filter, _ := conn.Locals("streamFilter").(streamFilter)
+
+	s := subscribeStream(filter)
+	defer s.unsubscribe()
+
+	// Reading is only needed to notice the client closing the connection.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		envelope, message := s.next(ticker.C, closed)
+
+		var err error
+		switch message {
+		case streamEvent:
+			err = conn.WriteJSON(envelope)
+		case streamPing:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
+		case streamClosed:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// droppedEvent is sent in place of events a slow client missed.
+type droppedEvent struct {
+	Count int64 `json:"count"`
+}
+
+func (droppedEvent) Topic() string { return droppedTopic }
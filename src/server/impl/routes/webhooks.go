@@ -0,0 +1,168 @@
+package routes
+
+import (
+	"anify/eltik/go/src/database"
+	events "anify/eltik/go/src/lib"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// webhookRequest is the body of POST /admin/webhooks.
+type webhookRequest struct {
+	URL string `json:"url"`
+	// Secret signs the payloads. A random one is generated when it is empty.
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// CreateWebhook registers a webhook. The response includes the secret, which is
+// not shown again.
+func (h *Handler) CreateWebhook(c *fiber.Ctx) error {
+	var body webhookRequest
+	if err := c.BodyParser(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid body: "+err.Error())
+	}
+
+	uri, err := url.Parse(body.URL)
+	if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") || uri.Host == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "url must be an absolute http or https URL")
+	}
+
+	for _, event := range body.Events {
+		if !isTopic(event) {
+			return fiber.NewError(fiber.StatusBadRequest, "unknown event: "+event)
+		}
+	}
+
+	if body.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		body.Secret = hex.EncodeToString(secret)
+	}
+
+	webhook, err := h.Repo.CreateWebhook(c.UserContext(), database.Webhook{
+		URL:    body.URL,
+		Secret: body.Secret,
+		Events: body.Events,
+		Active: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(webhook)
+}
+
+// ListWebhooks returns every registered webhook without its secret.
+func (h *Handler) ListWebhooks(c *fiber.Ctx) error {
+	webhooks, err := h.Repo.ListWebhooks(c.UserContext(), "")
+	if err != nil {
+		return err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return c.JSON(webhooks)
+}
+
+// DeleteWebhook removes a webhook and its delivery log.
+func (h *Handler) DeleteWebhook(c *fiber.Ctx) error {
+	id, err := parseID(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	deleted, err := h.Repo.DeleteWebhook(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// TestWebhook sends a single test event to a webhook and returns the attempt.
+func (h *Handler) TestWebhook(c *fiber.Ctx) error {
+	webhook, err := h.webhook(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := h.Webhooks.Test(c.UserContext(), *webhook)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(delivery)
+	}
+
+	return c.JSON(delivery)
+}
+
+// WebhookDeliveries returns the most recent delivery attempts of a webhook.
+// Supports ?limit=.
+func (h *Handler) WebhookDeliveries(c *fiber.Ctx) error {
+	webhook, err := h.webhook(c)
+	if err != nil {
+		return err
+	}
+
+	limit, err := parseInt(c.Query("limit"), 50)
+	if err != nil {
+		return err
+	}
+	if limit < 1 || limit > 500 {
+		return fiber.NewError(fiber.StatusBadRequest, "limit must be between 1 and 500")
+	}
+
+	deliveries, err := h.Repo.ListWebhookDeliveries(c.UserContext(), webhook.ID, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(deliveries)
+}
+
+// webhook loads the webhook named by the :id parameter.
+func (h *Handler) webhook(c *fiber.Ctx) (*database.Webhook, error) {
+	id, err := parseID(c.Params("id"))
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := h.Repo.GetWebhook(c.UserContext(), id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "webhook not found")
+	}
+
+	return webhook, nil
+}
+
+// parseID reads a numeric ID path parameter.
+func parseID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid id: "+value)
+	}
+	return id, nil
+}
+
+// isTopic reports whether a value is a known event topic.
+func isTopic(value string) bool {
+	for _, topic := range events.Topics {
+		if topic == value {
+			return true
+		}
+	}
+	return false
+}
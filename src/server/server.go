@@ -0,0 +1,50 @@
+package server
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/server/impl/routes"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// New creates the Fiber app with every API route registered.
+func New(repo database.Repository) *fiber.App {
+	app := fiber.New(fiber.Config{
+		AppName:      "Anify",
+		ErrorHandler: errorHandler,
+		// Search queries are path parameters, so "One%20Piece" has to reach the
+		// handlers as "One Piece".
+		UnescapePath: true,
+	})
+
+	routes.Register(app, repo)
+
+	return app
+}
+
+// Start creates the app and listens on the given port until it is shut down.
+func Start(port string, repo database.Repository) error {
+	app := New(repo)
+
+	fmt.Println("Starting server on port", port)
+
+	return app.Listen(":" + port)
+}
+
+// errorHandler writes every error as {"error": message, "status": code} so clients
+// always receive the same JSON shape.
+func errorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		code = e.Code
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"error":  err.Error(),
+		"status": code,
+	})
+}
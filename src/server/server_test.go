@@ -0,0 +1,38 @@
+package server
+
+import (
+	"anify/eltik/go/src/database"
+	"anify/eltik/go/src/types"
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+// searchRepository records the options of every search and returns one stored
+// manga, so the routes never fall back to the base provider.
+type searchRepository struct {
+	database.Repository
+	searches []database.SearchOptions
+}
+
+func (r *searchRepository) Search(ctx context.Context, opts database.SearchOptions) (interface{}, error) {
+	r.searches = append(r.searches, opts)
+	return []types.Manga{{ID: "manga-id"}}, nil
+}
+
+func TestSearchUnescapesQuery(t *testing.T) {
+	repo := &searchRepository{}
+	app := New(repo)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/search/manga/One%20Piece%3A%20Film", nil))
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	if len(repo.searches) != 1 || repo.searches[0].Query != "One Piece: Film" {
+		t.Errorf("searches = %+v, want one for %q", repo.searches, "One Piece: Film")
+	}
+}
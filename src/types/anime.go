@@ -2,7 +2,7 @@ package types
 
 import (
 	"anify/eltik/go/src/lib/impl/request"
-	"net/http"
+	"time"
 )
 
 type AnimeProvider interface {
@@ -10,7 +10,7 @@ type AnimeProvider interface {
 	FetchEpisodes(id string) ([]Episode, error)
 	FetchRecent() ([]Anime, error)
 	FetchSources(id string, proxy bool, chapter *Chapter) (interface{}, error) // can return []Page or string
-	Request(config http.Request, proxyRequest *bool) (request.Response, error)
+	Request(config request.Options, proxyRequest *bool) (request.Response, error)
 	ProxyCheck() (bool, error)
 	GetFormats() []Format
 	GetID() string
@@ -19,6 +19,7 @@ type AnimeProvider interface {
 
 type BaseAnimeProvider struct {
 	RateLimit          int
+	UserAgent          string
 	Id                 string
 	Url                string
 	Formats            []Format
@@ -45,7 +46,7 @@ func (b *BaseAnimeProvider) FetchSources(id string, proxy bool, chapter *Chapter
 	return nil, nil
 }
 
-func (b *BaseAnimeProvider) Request(config http.Request, proxyRequest *bool) (request.Response, error) {
+func (b *BaseAnimeProvider) Request(config request.Options, proxyRequest *bool) (request.Response, error) {
 	if proxyRequest == nil {
 		proxyRequest = &b.NeedsProxy
 	}
@@ -53,6 +54,11 @@ func (b *BaseAnimeProvider) Request(config http.Request, proxyRequest *bool) (re
 		*proxyRequest = false
 	}
 
+	request.SetRateLimit(b.Id, time.Duration(b.RateLimit)*time.Millisecond)
+	if b.UserAgent != "" {
+		request.SetUserAgent(b.Id, b.UserAgent)
+	}
+
 	resp, err := request.Request(b.Id, b.UseGoogleTranslate, config, *proxyRequest)
 	if err != nil {
 		return request.Response{}, err
@@ -62,7 +68,7 @@ func (b *BaseAnimeProvider) Request(config http.Request, proxyRequest *bool) (re
 }
 
 func (b *BaseAnimeProvider) ProxyCheck() (bool, error) {
-	return false, nil
+	return request.CheckProxy(b.Id, b.Url)
 }
 
 func (b *BaseAnimeProvider) GetFormats() []Format {
@@ -59,72 +59,80 @@ const (
 )
 
 type Title struct {
-	Romaji  *string
-	English *string
-	Native  *string
+	Romaji  *string `json:"romaji"`
+	English *string `json:"english"`
+	Native  *string `json:"native"`
 }
 
 type Mapping struct {
-	ID           string
-	ProviderID   string
-	Similarity   float64
-	ProviderType *string
+	ID           string  `json:"id"`
+	ProviderID   string  `json:"providerId"`
+	Similarity   float64 `json:"similarity"`
+	ProviderType *string `json:"providerType"`
 }
 
 type Artwork struct {
-	Type       string
-	Img        string
-	ProviderID string
+	Type       string `json:"type"`
+	Img        string `json:"img"`
+	ProviderID string `json:"providerId"`
 }
 
 type Character struct {
-	Name       string
-	Image      string
-	VoiceActor VoiceActor
+	Name       string     `json:"name"`
+	Image      string     `json:"image"`
+	VoiceActor VoiceActor `json:"voiceActor"`
 }
 
 type VoiceActor struct {
-	Name  string
-	Image string
+	Name  string `json:"name"`
+	Image string `json:"image"`
 }
 
 type Relations struct {
-	ID           string
-	Type         Type
-	Title        Title
-	Format       Format
-	RelationType string
+	ID           string `json:"id"`
+	Type         Type   `json:"type"`
+	Title        Title  `json:"title"`
+	Format       Format `json:"format"`
+	RelationType string `json:"relationType"`
 }
 
 type Episode struct {
-	ID          string
-	Title       string
-	Number      int
-	IsFiller    bool
-	Img         *string
-	HasDub      bool
-	Description *string
-	Rating      *float64
-	UpdatedAt   *int64
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Number      int      `json:"number"`
+	IsFiller    bool     `json:"isFiller"`
+	Img         *string  `json:"img"`
+	HasDub      bool     `json:"hasDub"`
+	Description *string  `json:"description"`
+	Rating      *float64 `json:"rating"`
+	UpdatedAt   *int64   `json:"updatedAt"`
 }
 
 type EpisodeData struct {
-	ProviderID string
-	Episodes   []Episode
+	ProviderID string    `json:"providerId"`
+	Episodes   []Episode `json:"episodes"`
 }
 
 type Chapter struct {
-	ID        string
-	Title     string
-	Number    int
-	Rating    *float64
-	UpdatedAt *int64
-	Mixdrop   *string
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Number    float64  `json:"number"`
+	Rating    *float64 `json:"rating"`
+	UpdatedAt *int64   `json:"updatedAt"`
+	Mixdrop   *string  `json:"mixdrop"`
+}
+
+// Page is a single image of a chapter. Headers lists any headers the image host
+// requires, such as a Referer.
+type Page struct {
+	URL     string            `json:"url"`
+	Index   int               `json:"index"`
+	Headers map[string]string `json:"headers"`
 }
 
 type ChapterData struct {
-	ProviderID string
-	Chapters   []Chapter
+	ProviderID string    `json:"providerId"`
+	Chapters   []Chapter `json:"chapters"`
 }
 
 type Status string
@@ -139,153 +147,313 @@ const (
 )
 
 type Media struct {
-	ID                string
-	Slug              string
-	CoverImage        *string
-	BannerImage       *string
-	Trailer           *string
-	Status            *Status
-	Season            Season
-	Title             Title
-	CurrentEpisode    *int
-	Mappings          []Mapping
-	Synonyms          []string
-	CountryOfOrigin   *string
-	Description       *string
-	Duration          *int
-	Color             *string
-	Year              *int
-	Rating            Rating
-	Popularity        Popularity
-	AverageRating     *float64
-	AveragePopularity *float64
-	Type              Type
-	Genres            []string
-	Format            Format
-	Relations         []Relations
-	TotalEpisodes     *int
-	Episodes          EpisodeCollection
-	Tags              []string
-	Artwork           []Artwork
-	Characters        []Character
-
-	CurrentChapter *int
-	TotalVolumes   *int
-	Publisher      *string
-	Author         *string
-	TotalChapters  *int
-	Chapters       ChapterCollection
+	ID                string            `json:"id"`
+	Slug              string            `json:"slug"`
+	CoverImage        *string           `json:"coverImage"`
+	BannerImage       *string           `json:"bannerImage"`
+	Trailer           *string           `json:"trailer"`
+	Status            *Status           `json:"status"`
+	Season            Season            `json:"season"`
+	Title             Title             `json:"title"`
+	CurrentEpisode    *int              `json:"currentEpisode"`
+	Mappings          []Mapping         `json:"mappings"`
+	Synonyms          []string          `json:"synonyms"`
+	CountryOfOrigin   *string           `json:"countryOfOrigin"`
+	Description       *string           `json:"description"`
+	Duration          *int              `json:"duration"`
+	Color             *string           `json:"color"`
+	Year              *int              `json:"year"`
+	Rating            Rating            `json:"rating"`
+	Popularity        Popularity        `json:"popularity"`
+	AverageRating     *float64          `json:"averageRating"`
+	AveragePopularity *float64          `json:"averagePopularity"`
+	Type              Type              `json:"type"`
+	Genres            []string          `json:"genres"`
+	Format            Format            `json:"format"`
+	Relations         []Relations       `json:"relations"`
+	TotalEpisodes     *int              `json:"totalEpisodes"`
+	Episodes          EpisodeCollection `json:"episodes"`
+	Tags              []string          `json:"tags"`
+	Artwork           []Artwork         `json:"artwork"`
+	Characters        []Character       `json:"characters"`
+
+	CurrentChapter *int              `json:"currentChapter"`
+	TotalVolumes   *int              `json:"totalVolumes"`
+	Publisher      *string           `json:"publisher"`
+	Author         *string           `json:"author"`
+	TotalChapters  *int              `json:"totalChapters"`
+	Chapters       ChapterCollection `json:"chapters"`
+
+	Provenance Provenance `json:"-"`
+}
+
+// ToAnime returns the anime fields of the media.
+func (m Media) ToAnime() Anime {
+	return Anime{
+		ID:                m.ID,
+		Slug:              m.Slug,
+		CoverImage:        m.CoverImage,
+		BannerImage:       m.BannerImage,
+		Trailer:           m.Trailer,
+		Status:            m.Status,
+		Season:            m.Season,
+		Title:             m.Title,
+		CurrentEpisode:    m.CurrentEpisode,
+		Mappings:          m.Mappings,
+		Synonyms:          m.Synonyms,
+		CountryOfOrigin:   m.CountryOfOrigin,
+		Description:       m.Description,
+		Duration:          m.Duration,
+		Color:             m.Color,
+		Year:              m.Year,
+		Rating:            m.Rating,
+		Popularity:        m.Popularity,
+		AverageRating:     m.AverageRating,
+		AveragePopularity: m.AveragePopularity,
+		Type:              m.Type,
+		Genres:            m.Genres,
+		Format:            m.Format,
+		Relations:         m.Relations,
+		TotalEpisodes:     m.TotalEpisodes,
+		Episodes:          m.Episodes,
+		Tags:              m.Tags,
+		Artwork:           m.Artwork,
+		Characters:        m.Characters,
+		Provenance:        m.Provenance,
+	}
+}
+
+// ToManga returns the manga fields of the media.
+func (m Media) ToManga() Manga {
+	return Manga{
+		ID:                m.ID,
+		Slug:              m.Slug,
+		CoverImage:        m.CoverImage,
+		BannerImage:       m.BannerImage,
+		Status:            m.Status,
+		Title:             m.Title,
+		Mappings:          m.Mappings,
+		Synonyms:          m.Synonyms,
+		CountryOfOrigin:   m.CountryOfOrigin,
+		Description:       m.Description,
+		CurrentChapter:    m.CurrentChapter,
+		TotalVolumes:      m.TotalVolumes,
+		Color:             m.Color,
+		Year:              m.Year,
+		Rating:            m.Rating,
+		Popularity:        m.Popularity,
+		AverageRating:     m.AverageRating,
+		AveragePopularity: m.AveragePopularity,
+		Genres:            m.Genres,
+		Type:              m.Type,
+		Format:            m.Format,
+		Relations:         m.Relations,
+		Publisher:         m.Publisher,
+		Author:            m.Author,
+		TotalChapters:     m.TotalChapters,
+		Chapters:          m.Chapters,
+		Tags:              m.Tags,
+		Artwork:           m.Artwork,
+		Characters:        m.Characters,
+		Provenance:        m.Provenance,
+	}
+}
+
+// ToMedia returns the anime as a media entry.
+func (a Anime) ToMedia() Media {
+	type_ := a.Type
+	if type_ == "" {
+		type_ = TypeAnime
+	}
+
+	return Media{
+		ID:                a.ID,
+		Slug:              a.Slug,
+		CoverImage:        a.CoverImage,
+		BannerImage:       a.BannerImage,
+		Trailer:           a.Trailer,
+		Status:            a.Status,
+		Season:            a.Season,
+		Title:             a.Title,
+		CurrentEpisode:    a.CurrentEpisode,
+		Mappings:          a.Mappings,
+		Synonyms:          a.Synonyms,
+		CountryOfOrigin:   a.CountryOfOrigin,
+		Description:       a.Description,
+		Duration:          a.Duration,
+		Color:             a.Color,
+		Year:              a.Year,
+		Rating:            a.Rating,
+		Popularity:        a.Popularity,
+		AverageRating:     a.AverageRating,
+		AveragePopularity: a.AveragePopularity,
+		Type:              type_,
+		Genres:            a.Genres,
+		Format:            a.Format,
+		Relations:         a.Relations,
+		TotalEpisodes:     a.TotalEpisodes,
+		Episodes:          a.Episodes,
+		Tags:              a.Tags,
+		Artwork:           a.Artwork,
+		Characters:        a.Characters,
+		Provenance:        a.Provenance,
+	}
+}
+
+// ToMedia returns the manga as a media entry.
+func (m Manga) ToMedia() Media {
+	type_ := m.Type
+	if type_ == "" {
+		type_ = TypeManga
+	}
+
+	return Media{
+		ID:                m.ID,
+		Slug:              m.Slug,
+		CoverImage:        m.CoverImage,
+		BannerImage:       m.BannerImage,
+		Status:            m.Status,
+		Title:             m.Title,
+		Mappings:          m.Mappings,
+		Synonyms:          m.Synonyms,
+		CountryOfOrigin:   m.CountryOfOrigin,
+		Description:       m.Description,
+		CurrentChapter:    m.CurrentChapter,
+		TotalVolumes:      m.TotalVolumes,
+		Color:             m.Color,
+		Year:              m.Year,
+		Rating:            m.Rating,
+		Popularity:        m.Popularity,
+		AverageRating:     m.AverageRating,
+		AveragePopularity: m.AveragePopularity,
+		Genres:            m.Genres,
+		Type:              type_,
+		Format:            m.Format,
+		Relations:         m.Relations,
+		Publisher:         m.Publisher,
+		Author:            m.Author,
+		TotalChapters:     m.TotalChapters,
+		Chapters:          m.Chapters,
+		Tags:              m.Tags,
+		Artwork:           m.Artwork,
+		Characters:        m.Characters,
+		Provenance:        m.Provenance,
+	}
 }
 
 type Anime struct {
-	ID                string
-	Slug              string
-	CoverImage        *string
-	BannerImage       *string
-	Trailer           *string
-	Status            *Status
-	Season            Season
-	Title             Title
-	CurrentEpisode    *int
-	Mappings          []Mapping
-	Synonyms          []string
-	CountryOfOrigin   *string
-	Description       *string
-	Duration          *int
-	Color             *string
-	Year              *int
-	Rating            Rating
-	Popularity        Popularity
-	AverageRating     *float64
-	AveragePopularity *float64
-	Type              Type
-	Genres            []string
-	Format            Format
-	Relations         []Relations
-	TotalEpisodes     *int
-	Episodes          EpisodeCollection
-	Tags              []string
-	Artwork           []Artwork
-	Characters        []Character
+	ID                string            `json:"id"`
+	Slug              string            `json:"slug"`
+	CoverImage        *string           `json:"coverImage"`
+	BannerImage       *string           `json:"bannerImage"`
+	Trailer           *string           `json:"trailer"`
+	Status            *Status           `json:"status"`
+	Season            Season            `json:"season"`
+	Title             Title             `json:"title"`
+	CurrentEpisode    *int              `json:"currentEpisode"`
+	Mappings          []Mapping         `json:"mappings"`
+	Synonyms          []string          `json:"synonyms"`
+	CountryOfOrigin   *string           `json:"countryOfOrigin"`
+	Description       *string           `json:"description"`
+	Duration          *int              `json:"duration"`
+	Color             *string           `json:"color"`
+	Year              *int              `json:"year"`
+	Rating            Rating            `json:"rating"`
+	Popularity        Popularity        `json:"popularity"`
+	AverageRating     *float64          `json:"averageRating"`
+	AveragePopularity *float64          `json:"averagePopularity"`
+	Type              Type              `json:"type"`
+	Genres            []string          `json:"genres"`
+	Format            Format            `json:"format"`
+	Relations         []Relations       `json:"relations"`
+	TotalEpisodes     *int              `json:"totalEpisodes"`
+	Episodes          EpisodeCollection `json:"episodes"`
+	Tags              []string          `json:"tags"`
+	Artwork           []Artwork         `json:"artwork"`
+	Characters        []Character       `json:"characters"`
+	// Provenance is served by /info/:id/provenance instead of with the entry.
+	Provenance Provenance `json:"-"`
 }
 
 type Manga struct {
-	ID                string
-	Slug              string
-	CoverImage        *string
-	BannerImage       *string
-	Status            *Status
-	Title             Title
-	Mappings          []Mapping
-	Synonyms          []string
-	CountryOfOrigin   *string
-	Description       *string
-	CurrentChapter    *int
-	TotalVolumes      *int
-	Color             *string
-	Year              *int
-	Rating            Rating
-	Popularity        Popularity
-	AverageRating     *float64
-	AveragePopularity *float64
-	Genres            []string
-	Type              Type
-	Format            Format
-	Relations         []Relations
-	Publisher         *string
-	Author            *string
-	TotalChapters     *int
-	Chapters          ChapterCollection
-	Tags              []string
-	Artwork           []Artwork
-	Characters        []Character
+	ID                string            `json:"id"`
+	Slug              string            `json:"slug"`
+	CoverImage        *string           `json:"coverImage"`
+	BannerImage       *string           `json:"bannerImage"`
+	Status            *Status           `json:"status"`
+	Title             Title             `json:"title"`
+	Mappings          []Mapping         `json:"mappings"`
+	Synonyms          []string          `json:"synonyms"`
+	CountryOfOrigin   *string           `json:"countryOfOrigin"`
+	Description       *string           `json:"description"`
+	CurrentChapter    *int              `json:"currentChapter"`
+	TotalVolumes      *int              `json:"totalVolumes"`
+	Color             *string           `json:"color"`
+	Year              *int              `json:"year"`
+	Rating            Rating            `json:"rating"`
+	Popularity        Popularity        `json:"popularity"`
+	AverageRating     *float64          `json:"averageRating"`
+	AveragePopularity *float64          `json:"averagePopularity"`
+	Genres            []string          `json:"genres"`
+	Type              Type              `json:"type"`
+	Format            Format            `json:"format"`
+	Relations         []Relations       `json:"relations"`
+	Publisher         *string           `json:"publisher"`
+	Author            *string           `json:"author"`
+	TotalChapters     *int              `json:"totalChapters"`
+	Chapters          ChapterCollection `json:"chapters"`
+	Tags              []string          `json:"tags"`
+	Artwork           []Artwork         `json:"artwork"`
+	Characters        []Character       `json:"characters"`
+	// Provenance is served by /info/:id/provenance instead of with the entry.
+	Provenance Provenance `json:"-"`
 }
 
 type EpisodeCollection struct {
 	Latest struct {
-		UpdatedAt     int64
-		LatestEpisode int
-		LatestTitle   string
-	}
-	Data []EpisodeData
+		UpdatedAt     int64  `json:"updatedAt"`
+		LatestEpisode int    `json:"latestEpisode"`
+		LatestTitle   string `json:"latestTitle"`
+	} `json:"latest"`
+	Data []EpisodeData `json:"data"`
 }
 
 type ChapterCollection struct {
 	Latest struct {
-		UpdatedAt     int64
-		LatestChapter int
-		LatestTitle   string
-	}
-	Data []ChapterData
+		UpdatedAt     int64   `json:"updatedAt"`
+		LatestChapter float64 `json:"latestChapter"`
+		LatestTitle   string  `json:"latestTitle"`
+	} `json:"latest"`
+	Data []ChapterData `json:"data"`
 }
 
 type MediaInfo struct {
-	ID              string
-	Title           Title
-	Artwork         []Artwork
-	Synonyms        []string
-	TotalEpisodes   *int
-	CurrentEpisode  *int
-	BannerImage     *string
-	CoverImage      *string
-	Color           *string
-	Season          Season
-	Year            *int
-	Status          *string
-	Genres          []string
-	Description     *string
-	Format          Format
-	Duration        *int
-	Trailer         *string
-	CountryOfOrigin *string
-	Tags            []string
-	Relations       []Relations
-	Characters      []Character
-	Type            Type
-	Rating          *float64
-	Popularity      *float64
-	TotalChapters   *int
-	TotalVolumes    *int
-	Author          *string
-	Publisher       *string
+	ID              string      `json:"id"`
+	Title           Title       `json:"title"`
+	Artwork         []Artwork   `json:"artwork"`
+	Synonyms        []string    `json:"synonyms"`
+	TotalEpisodes   *int        `json:"totalEpisodes"`
+	CurrentEpisode  *int        `json:"currentEpisode"`
+	BannerImage     *string     `json:"bannerImage"`
+	CoverImage      *string     `json:"coverImage"`
+	Color           *string     `json:"color"`
+	Season          Season      `json:"season"`
+	Year            *int        `json:"year"`
+	Status          *string     `json:"status"`
+	Genres          []string    `json:"genres"`
+	Description     *string     `json:"description"`
+	Format          Format      `json:"format"`
+	Duration        *int        `json:"duration"`
+	Trailer         *string     `json:"trailer"`
+	CountryOfOrigin *string     `json:"countryOfOrigin"`
+	Tags            []string    `json:"tags"`
+	Relations       []Relations `json:"relations"`
+	Characters      []Character `json:"characters"`
+	Type            Type        `json:"type"`
+	Rating          *float64    `json:"rating"`
+	Popularity      *float64    `json:"popularity"`
+	TotalChapters   *int        `json:"totalChapters"`
+	TotalVolumes    *int        `json:"totalVolumes"`
+	Author          *string     `json:"author"`
+	Publisher       *string     `json:"publisher"`
 }
@@ -2,7 +2,7 @@ package types
 
 import (
 	"anify/eltik/go/src/lib/impl/request"
-	"net/http"
+	"time"
 )
 
 type SeasonalResponse struct {
@@ -30,13 +30,15 @@ type BaseProvider interface {
 	GetSeasonal(mediaType Type, formats []Format) (SeasonalResponse, error)
 	GetSchedule() (ScheduleResponse, error)
 	GetIds() ([]string, error)
-	Request(config http.Request, proxyRequest *bool) (request.Response, error)
+	Request(config request.Options, proxyRequest *bool) (request.Response, error)
 	ProxyCheck() (bool, error)
 	GetFormats() []Format
+	GetID() string
 }
 
 type BaseBaseProvider struct {
 	RateLimit          int
+	UserAgent          string
 	Id                 string
 	Url                string
 	Formats            []Format
@@ -79,7 +81,11 @@ func (b *BaseBaseProvider) GetFormats() []Format {
 	return b.Formats
 }
 
-func (b *BaseBaseProvider) Request(config http.Request, proxyRequest *bool) (request.Response, error) {
+func (b *BaseBaseProvider) GetID() string {
+	return b.Id
+}
+
+func (b *BaseBaseProvider) Request(config request.Options, proxyRequest *bool) (request.Response, error) {
 	if proxyRequest == nil {
 		proxyRequest = &b.NeedsProxy
 	}
@@ -87,6 +93,11 @@ func (b *BaseBaseProvider) Request(config http.Request, proxyRequest *bool) (req
 		*proxyRequest = false
 	}
 
+	request.SetRateLimit(b.Id, time.Duration(b.RateLimit)*time.Millisecond)
+	if b.UserAgent != "" {
+		request.SetUserAgent(b.Id, b.UserAgent)
+	}
+
 	resp, err := request.Request(b.Id, b.UseGoogleTranslate, config, *proxyRequest)
 	if err != nil {
 		return request.Response{}, err
@@ -96,5 +107,5 @@ func (b *BaseBaseProvider) Request(config http.Request, proxyRequest *bool) (req
 }
 
 func (b *BaseBaseProvider) ProxyCheck() (bool, error) {
-	return false, nil
+	return request.CheckProxy(b.Id, b.Url)
 }
@@ -2,14 +2,14 @@ package types
 
 import (
 	"anify/eltik/go/src/lib/impl/request"
-	"net/http"
+	"time"
 )
 
 type MediaInfoKeys []string
 
 type InformationProvider[T Media, U MediaInfo] interface {
 	Info(media T) (U, error)
-	Request(config http.Request, proxyRequest *bool) (request.Response, error)
+	Request(config request.Options, proxyRequest *bool) (request.Response, error)
 	GetSharedArea() MediaInfoKeys
 	GetPriorityArea() MediaInfoKeys
 	ProxyCheck() (bool, error)
@@ -18,6 +18,8 @@ type InformationProvider[T Media, U MediaInfo] interface {
 }
 
 type BaseInformationProvider struct {
+	RateLimit          int
+	UserAgent          string
 	Id                 string
 	Url                string
 	ProviderType       ProviderType
@@ -39,7 +41,7 @@ func (b *BaseInformationProvider) GetSharedArea() MediaInfoKeys {
 	return MediaInfoKeys{}
 }
 
-func (b *BaseInformationProvider) Request(config http.Request, proxyRequest *bool) (request.Response, error) {
+func (b *BaseInformationProvider) Request(config request.Options, proxyRequest *bool) (request.Response, error) {
 	if proxyRequest == nil {
 		proxyRequest = &b.NeedsProxy
 	}
@@ -47,6 +49,11 @@ func (b *BaseInformationProvider) Request(config http.Request, proxyRequest *boo
 		*proxyRequest = false
 	}
 
+	request.SetRateLimit(b.Id, time.Duration(b.RateLimit)*time.Millisecond)
+	if b.UserAgent != "" {
+		request.SetUserAgent(b.Id, b.UserAgent)
+	}
+
 	resp, err := request.Request(b.Id, b.UseGoogleTranslate, config, *proxyRequest)
 	if err != nil {
 		return request.Response{}, err
@@ -56,7 +63,7 @@ func (b *BaseInformationProvider) Request(config http.Request, proxyRequest *boo
 }
 
 func (b *BaseInformationProvider) ProxyCheck() (bool, error) {
-	return false, nil
+	return request.CheckProxy(b.Id, b.Url)
 }
 
 func (b *BaseInformationProvider) GetID() string {
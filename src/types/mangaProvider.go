@@ -2,16 +2,16 @@ package types
 
 import (
 	"anify/eltik/go/src/lib/impl/request"
-	"net/http"
 	"strings"
+	"time"
 )
 
 type MangaProvider interface {
 	Search(query string, format Format, year int) ([]Result, error)
 	FetchChapters(id string) ([]Chapter, error)
 	FetchRecent() ([]Manga, error)
-	FetchPages(id string, proxy bool, chapter *Chapter) (interface{}, error) // can return []Page or string
-	Request(config http.Request, proxyRequest *bool) (request.Response, error)
+	FetchPages(id string, proxy bool, dataSaver bool) ([]Page, error)
+	Request(config request.Options, proxyRequest *bool) (request.Response, error)
 	ProxyCheck() (bool, error)
 	PadNum(number string, places int) string
 	GetFormats() []Format
@@ -21,6 +21,7 @@ type MangaProvider interface {
 
 type BaseMangaProvider struct {
 	RateLimit          int
+	UserAgent          string
 	Id                 string
 	Url                string
 	Formats            []Format
@@ -43,11 +44,11 @@ func (b *BaseMangaProvider) FetchRecent() ([]Manga, error) {
 	return nil, nil
 }
 
-func (b *BaseMangaProvider) FetchPages(id string, proxy bool, chapter *Chapter) (interface{}, error) {
+func (b *BaseMangaProvider) FetchPages(id string, proxy bool, dataSaver bool) ([]Page, error) {
 	return nil, nil
 }
 
-func (b *BaseMangaProvider) Request(config http.Request, proxyRequest *bool) (request.Response, error) {
+func (b *BaseMangaProvider) Request(config request.Options, proxyRequest *bool) (request.Response, error) {
 	if proxyRequest == nil {
 		proxyRequest = &b.NeedsProxy
 	}
@@ -55,6 +56,11 @@ func (b *BaseMangaProvider) Request(config http.Request, proxyRequest *bool) (re
 		*proxyRequest = false
 	}
 
+	request.SetRateLimit(b.Id, time.Duration(b.RateLimit)*time.Millisecond)
+	if b.UserAgent != "" {
+		request.SetUserAgent(b.Id, b.UserAgent)
+	}
+
 	resp, err := request.Request(b.Id, b.UseGoogleTranslate, config, *proxyRequest)
 	if err != nil {
 		return request.Response{}, err
@@ -64,7 +70,7 @@ func (b *BaseMangaProvider) Request(config http.Request, proxyRequest *bool) (re
 }
 
 func (b *BaseMangaProvider) ProxyCheck() (bool, error) {
-	return false, nil
+	return request.CheckProxy(b.Id, b.Url)
 }
 
 func (b *BaseMangaProvider) PadNum(number string, places int) string {
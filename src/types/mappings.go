@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type MappingsProviders struct {
 	AnimeProviders []AnimeProvider `json:"animeProviders"`
 	MangaProviders []MangaProvider `json:"mangaProviders"`
@@ -29,3 +31,79 @@ type ToPush struct {
 	ProviderType ProviderType `json:"provider_type"`
 	Similarity   float64      `json:"similarity"`
 }
+
+// Match rules record why a mapping candidate was accepted or rejected.
+const (
+	// MatchAccepted means the candidate passed every rule and was mapped.
+	MatchAccepted = "accepted"
+	// MatchNotBest means another candidate of the same search scored higher.
+	MatchNotBest = "not-best"
+	// MatchRatingThreshold means the best title rating was below MinRating.
+	MatchRatingThreshold = "rating-threshold"
+	// MatchFormatMismatch means the candidate's format differs from the base format.
+	MatchFormatMismatch = "format-mismatch"
+	// MatchYearMismatch means the candidate's year differs from the base year.
+	MatchYearMismatch = "year-mismatch"
+	// MatchSimilarityThreshold means the similarity to the base titles was below
+	// MinSimilarity.
+	MatchSimilarityThreshold = "similarity-threshold"
+	// MatchDuplicate means the same provider ID was already mapped.
+	MatchDuplicate = "duplicate"
+	// MatchBlocked means the pairing is blocked by a mapping override.
+	MatchBlocked = "blocked"
+	// MatchPinned means the provider ID was pinned by a mapping override.
+	MatchPinned = "pinned"
+)
+
+// Match report outcomes.
+const (
+	MatchOutcomeMapped     = "mapped"
+	MatchOutcomeNoMappings = "no-mappings"
+	MatchOutcomeNotFound   = "not-found"
+	MatchOutcomeFailed     = "failed"
+)
+
+// MatchReport explains the decisions made by one run of the mapping pipeline.
+type MatchReport struct {
+	MediaID      string   `json:"mediaId"`
+	Type         Type     `json:"type"`
+	Formats      []Format `json:"formats"`
+	BaseProvider string   `json:"baseProvider"`
+	// Titles are the base provider's titles and synonyms candidates are compared to.
+	Titles     []string       `json:"titles"`
+	Searches   []SearchReport `json:"searches"`
+	Outcome    string         `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+	Mapped     int            `json:"mapped"`
+	DurationMs int64          `json:"durationMs"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+// SearchReport covers the candidates one provider returned for one query.
+type SearchReport struct {
+	ProviderID    string  `json:"providerId"`
+	Query         string  `json:"query"`
+	Matcher       string  `json:"matcher"`
+	MinRating     float64 `json:"minRating"`
+	MinSimilarity float64 `json:"minSimilarity"`
+	// Override is "pin" or "none" when the provider was not searched because of a
+	// mapping override.
+	Override   string            `json:"override,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Candidates []CandidateReport `json:"candidates"`
+}
+
+// CandidateReport is a single search result and the rule that decided it.
+type CandidateReport struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Format Format `json:"format"`
+	Year   int    `json:"year"`
+	// Rating is the candidate's best title score against the base titles.
+	Rating float64 `json:"rating"`
+	// MatchedTitle is the candidate title that got the rating.
+	MatchedTitle string `json:"matchedTitle"`
+	// Similarity is only computed for the best candidate of a search.
+	Similarity *float64 `json:"similarity,omitempty"`
+	Rule       string   `json:"rule"`
+}
@@ -0,0 +1,68 @@
+package types
+
+import "time"
+
+// Provenance actions describe what a provider's value did to a field.
+const (
+	// ProvenanceSet filled in a field that was empty.
+	ProvenanceSet = "set"
+	// ProvenanceMerged added new entries to a list field.
+	ProvenanceMerged = "merged"
+	// ProvenanceOverwrote replaced the field because it is in the provider's
+	// priority area.
+	ProvenanceOverwrote = "overwrote"
+	// ProvenanceKept left the field as it was, either because it already had a
+	// value or because the value was the same.
+	ProvenanceKept = "kept"
+)
+
+// FieldSource records the value a provider supplied for one media field.
+type FieldSource struct {
+	ProviderID string      `json:"providerId"`
+	FetchedAt  time.Time   `json:"fetchedAt"`
+	Action     string      `json:"action"`
+	Value      interface{} `json:"value"`
+}
+
+// maxFieldSources is how many sources are kept per field. The oldest ones are
+// dropped first, except for the source of the current value.
+const maxFieldSources = 10
+
+// Provenance maps a field name, such as "description" or "coverImage", to the values
+// providers supplied for it, oldest first.
+type Provenance map[string][]FieldSource
+
+// Record appends a provider's value for a field.
+func (p Provenance) Record(field string, source FieldSource) {
+	sources := append(p[field], source)
+
+	for len(sources) > maxFieldSources {
+		drop := 0
+		if drop == currentIndex(sources) {
+			drop = 1
+		}
+		sources = append(sources[:drop], sources[drop+1:]...)
+	}
+
+	p[field] = sources
+}
+
+// Current returns the provider that supplied the field's current value, or "" when
+// no provider changed it.
+func (p Provenance) Current(field string) string {
+	sources := p[field]
+	if i := currentIndex(sources); i >= 0 {
+		return sources[i].ProviderID
+	}
+	return ""
+}
+
+// currentIndex returns the index of the last source that changed the field, or -1.
+func currentIndex(sources []FieldSource) int {
+	for i := len(sources) - 1; i >= 0; i-- {
+		if sources[i].Action != ProvenanceKept {
+			return i
+		}
+	}
+	return -1
+}